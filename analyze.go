@@ -8,6 +8,7 @@ import (
 	"github.com/256dpi/xo"
 	"github.com/samber/lo"
 
+	"github.com/256dpi/mediakit/cache"
 	"github.com/256dpi/mediakit/ffmpeg"
 	"github.com/256dpi/mediakit/vips"
 )
@@ -35,10 +36,50 @@ type Report struct {
 
 	// video/animation
 	FrameRate float64 `json:"frameRate"`
+
+	// animation
+	Frames int `json:"frames,omitempty"`
 }
 
-// Analyze will analyze the provided file and return a report.
+// Cache is an optional store used to cache analysis reports by the content
+// hash of the analyzed file.
+var Cache cache.Store
+
+// Analyze will analyze the provided file and return a report. If a cache is
+// configured, reports are cached.
 func Analyze(ctx context.Context, input *os.File) (*Report, error) {
+	// analyze input if cache is absent
+	if Cache == nil {
+		return analyze(ctx, input)
+	}
+
+	// check cache
+	var report Report
+	key, ok, err := cache.Lookup(Cache, "mediakit:", input, &report)
+	if err != nil {
+		return nil, xo.W(err)
+	} else if ok {
+		return &report, nil
+	}
+
+	// analyze input
+	rep, err := analyze(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// cache report
+	if key != "" {
+		err = cache.Save(Cache, key, rep)
+		if err != nil {
+			return nil, xo.W(err)
+		}
+	}
+
+	return rep, nil
+}
+
+func analyze(ctx context.Context, input *os.File) (*Report, error) {
 	// detect media stream
 	mediaType, _, err := DetectStream(input, false)
 	if err != nil {
@@ -58,62 +99,78 @@ func Analyze(ctx context.Context, input *os.File) (*Report, error) {
 			return nil, xo.W(err)
 		}
 
-		// get size
-		width, height := rep.Size()
-
-		// get codes and channels
-		var streams []string
-		var codecs []string
-		var channels int
-		for _, stream := range rep.Streams {
-			if stream.Type != "data" {
-				streams = append(streams, stream.Type)
-				codecs = append(codecs, stream.Codec)
-				if stream.Channels > channels {
-					channels = stream.Channels
-				}
-			}
-		}
-
-		return &Report{
-			MediaType:  mediaType,
-			FileFormat: rep.Format.Name,
-			Width:      width,
-			Height:     height,
-			Streams:    streams,
-			Codecs:     codecs,
-			Duration:   rep.Duration,
-			Channels:   channels,
-			SampleRate: rep.SampleRate(),
-			FrameRate:  rep.FrameRate(),
-		}, nil
+		return ffmpegReport(mediaType, rep), nil
 	} else if lo.Contains(ImageTypes(), mediaType) {
 		rep, err := vips.Analyze(ctx, input)
 		if err != nil {
 			return nil, xo.W(err)
 		}
 
-		// calculate duration
-		var duration int
-		var frameRate float64
-		for _, delay := range rep.Delay {
-			duration += delay
-		}
-		if duration > 0 {
-			frameRate = 1000 / (float64(duration) / float64(rep.Pages))
-		}
-
-		return &Report{
-			MediaType:  mediaType,
-			FileFormat: rep.Format,
-			Width:      rep.Width,
-			Height:     rep.Height,
-			Duration:   float64(duration) / 1000,
-			FrameRate:  frameRate,
-		}, nil
+		return vipsReport(mediaType, rep), nil
 	}
 
 	return &Report{
 		MediaType: mediaType,
 	}, nil
 }
+
+func ffmpegReport(mediaType string, rep *ffmpeg.Report) *Report {
+	// get size
+	width, height := rep.Size()
+
+	// get codes and channels
+	var streams []string
+	var codecs []string
+	var channels int
+	for _, stream := range rep.Streams {
+		if stream.Type != "data" {
+			streams = append(streams, stream.Type)
+			codecs = append(codecs, stream.Codec)
+			if stream.Channels > channels {
+				channels = stream.Channels
+			}
+		}
+	}
+
+	return &Report{
+		MediaType:  mediaType,
+		FileFormat: rep.Format.Name,
+		Width:      width,
+		Height:     height,
+		Streams:    streams,
+		Codecs:     codecs,
+		Duration:   rep.Duration,
+		Channels:   channels,
+		SampleRate: rep.SampleRate(),
+		FrameRate:  rep.FrameRate(),
+		Frames:     rep.Frames,
+	}
+}
+
+func vipsReport(mediaType string, rep *vips.Report) *Report {
+	// calculate duration
+	var duration int
+	var frameRate float64
+	for _, delay := range rep.Delay {
+		duration += delay
+	}
+	if duration > 0 {
+		frameRate = 1000 / (float64(duration) / float64(rep.Pages))
+	}
+
+	// count frames of animations
+	var frames int
+	if len(rep.Delay) > 0 {
+		frames = rep.Pages
+	}
+
+	return &Report{
+		MediaType:  mediaType,
+		FileFormat: rep.Format,
+		Width:      rep.Width,
+		Height:     rep.Height,
+		Duration:   float64(duration) / 1000,
+		FrameRate:  frameRate,
+		Frames:     frames,
+	}
+}
@@ -1,10 +1,12 @@
 package mediakit
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/256dpi/mediakit/cache"
 	"github.com/256dpi/mediakit/samples"
 )
 
@@ -105,6 +107,7 @@ func TestAnalyze(t *testing.T) {
 				Height:     450,
 				Duration:   2,
 				FrameRate:  5,
+				Frames:     10,
 			},
 		},
 		{
@@ -116,6 +119,7 @@ func TestAnalyze(t *testing.T) {
 				Height:     450,
 				Duration:   2,
 				FrameRate:  5,
+				Frames:     10,
 			},
 		},
 		// audio
@@ -400,3 +404,23 @@ func TestAnalyze(t *testing.T) {
 		assert.Equal(t, &item.report, report, item.sample)
 	}
 }
+
+func TestAnalyzePipe(t *testing.T) {
+	store := cache.NewMemory(10)
+	Cache = store
+	defer func() {
+		Cache = nil
+	}()
+
+	pr, pw, err := os.Pipe()
+	assert.NoError(t, err)
+	defer pr.Close()
+
+	go func() {
+		_, _ = pw.Write(samples.Read(samples.ImagePNG))
+		_ = pw.Close()
+	}()
+
+	_, _ = Analyze(nil, pr)
+	assert.Equal(t, 0, store.Len())
+}
@@ -1,28 +1,230 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
-	"github.com/256dpi/xo"
 	"github.com/kr/pretty"
+	"github.com/samber/lo"
 
 	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/ffmpeg"
+	"github.com/256dpi/mediakit/vips"
 )
 
+var format = flag.String("format", "pretty", "the output format: pretty, json or ndjson")
+var level = flag.String("level", "mediakit", "the report level: mediakit, raw, ffmpeg or vips")
+var recursive = flag.Bool("recursive", false, "whether to descend into subdirectories")
+var include = flag.String("include", "", "comma separated glob patterns file names must match")
+var exclude = flag.String("exclude", "", "comma separated glob patterns file names must not match")
+var jobs = flag.Int("jobs", runtime.NumCPU(), "the number of files analyzed in parallel")
+
+type row struct {
+	Path   string `json:"path"`
+	Report any    `json:"report,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
 func main() {
 	// parse flags
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: mk-analyze [flags] <path>...")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// check flags
+	if !lo.Contains([]string{"pretty", "json", "ndjson"}, *format) {
+		fatal(fmt.Errorf("invalid format: %s", *format))
+	} else if !lo.Contains([]string{"mediakit", "raw", "ffmpeg", "vips"}, *level) {
+		fatal(fmt.Errorf("invalid level: %s", *level))
+	}
+
+	// collect files
+	var paths []string
+	for _, arg := range flag.Args() {
+		list, err := collect(arg)
+		if err != nil {
+			fatal(err)
+		}
+		paths = append(paths, list...)
+	}
+
+	// prepare rows
+	rows := make([]row, len(paths))
+	done := make([]chan struct{}, len(paths))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	// analyze files
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range max(*jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				rows[i] = analyze(paths[i])
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			queue <- i
+		}
+		close(queue)
+	}()
+
+	// write rows in order
+	failed := false
+	encoder := json.NewEncoder(os.Stdout)
+	for i := range rows {
+		<-done[i]
+		failed = failed || rows[i].Error != ""
+		switch *format {
+		case "pretty":
+			if rows[i].Error != "" {
+				fmt.Printf("%s: error: %s\n", rows[i].Path, rows[i].Error)
+			} else if len(rows) == 1 {
+				pretty.Println(rows[i].Report)
+			} else {
+				fmt.Printf("%s: %# v\n", rows[i].Path, pretty.Formatter(rows[i].Report))
+			}
+		case "ndjson":
+			_ = encoder.Encode(rows[i])
+		}
+	}
+	if *format == "json" {
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(rows)
+	}
+
+	// wait for workers
+	wg.Wait()
+
+	// signal failure
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func collect(path string) ([]string, error) {
+	// get info
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// handle files
+	if !info.IsDir() {
+		if !matches(path) {
+			return nil, nil
+		}
+		return []string{path}, nil
+	}
 
+	// walk directory
+	var list []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// handle directories
+		if entry.IsDir() {
+			if file != path && !*recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// add regular files
+		if entry.Type().IsRegular() && matches(file) {
+			list = append(list, file)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func matches(path string) bool {
+	// check patterns
+	name := filepath.Base(path)
+	match := func(patterns string) bool {
+		for _, pattern := range strings.Split(patterns, ",") {
+			if ok, _ := filepath.Match(strings.TrimSpace(pattern), name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return (*include == "" || match(*include)) && (*exclude == "" || !match(*exclude))
+}
+
+func analyze(path string) row {
 	// open file
-	file, err := os.Open(flag.Arg(0))
-	xo.PanicIf(err)
+	file, err := os.Open(path)
+	if err != nil {
+		return row{Path: path, Error: err.Error()}
+	}
 	defer file.Close()
 
+	// select level
+	lvl := *level
+	if lvl == "raw" {
+		mediaType, _, err := mediakit.DetectStream(file, false)
+		if err != nil {
+			return row{Path: path, Error: err.Error()}
+		}
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return row{Path: path, Error: err.Error()}
+		}
+		lvl = "ffmpeg"
+		if lo.Contains(mediakit.ImageTypes(), mediaType) {
+			lvl = "vips"
+		}
+	}
+
 	// analyze file
-	report, err := mediakit.Analyze(nil, file)
-	xo.PanicIf(err)
+	var report any
+	switch lvl {
+	case "mediakit":
+		report, err = mediakit.Analyze(context.Background(), file)
+	case "ffmpeg":
+		report, err = ffmpeg.Analyze(context.Background(), file)
+	case "vips":
+		report, err = vips.Analyze(context.Background(), file)
+	}
+	if err != nil {
+		return row{Path: path, Error: err.Error()}
+	}
+
+	return row{Path: path, Report: report}
+}
 
-	// print report
-	pretty.Println(report)
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "mk-analyze: %s\n", err.Error())
+	os.Exit(2)
 }
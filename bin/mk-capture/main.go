@@ -4,9 +4,11 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/256dpi/mediakit"
 	"github.com/256dpi/mediakit/chromium"
+	"github.com/256dpi/mediakit/ffmpeg"
 )
 
 var mode = flag.String("mode", "image", "")
@@ -16,6 +18,33 @@ var scale = flag.Float64("scale", 2, "")
 var full = flag.Bool("full", false, "")
 var pedantic = flag.Bool("pedantic", false, "")
 var wait = flag.Duration("wait", 0, "")
+var format = flag.String("format", "png", "")
+var quality = flag.Int("quality", 0, "")
+var tiled = flag.Bool("tiled", false, "")
+var paper = flag.String("paper", "letter", "")
+var margin = flag.Float64("margin", 0, "")
+var landscape = flag.Bool("landscape", false, "")
+var header = flag.String("header", "", "")
+var footer = flag.String("footer", "", "")
+var background = flag.Bool("background", false, "")
+var pages = flag.String("pages", "", "")
+var block = flag.String("block", "", "")
+var trackers = flag.Bool("trackers", false, "")
+var offline = flag.Bool("offline", false, "")
+var mhtml = flag.String("mhtml", "", "")
+var warc = flag.String("warc", "", "")
+var fps = flag.Float64("fps", 0, "")
+var duration = flag.Duration("duration", 0, "")
+var preset = flag.String("preset", "VideoMP4H264AACFast", "")
+
+var papers = map[string]chromium.Paper{
+	"letter":  chromium.Letter,
+	"legal":   chromium.Legal,
+	"tabloid": chromium.Tabloid,
+	"a3":      chromium.A3,
+	"a4":      chromium.A4,
+	"a5":      chromium.A5,
+}
 
 func main() {
 	// parse flags
@@ -38,17 +67,59 @@ func main() {
 	}
 	defer output.Close()
 
+	// prepare blocking
+	var blocking *chromium.Blocking
+	if *block != "" || *trackers || *offline {
+		blocking = &chromium.Blocking{Offline: *offline}
+		if *block != "" {
+			blocking.Patterns = strings.Split(*block, ",")
+		}
+		if *trackers {
+			blocking.Patterns = append(blocking.Patterns, chromium.Trackers...)
+		}
+	}
+
 	// convert input
 	switch *mode {
 	case "image":
-		err = mediakit.CaptureScreenshot(nil, inURL, output, chromium.ScreenshotOptions{
+		opts := chromium.ScreenshotOptions{
 			Width:    *width,
 			Height:   *height,
 			Scale:    *scale,
 			Full:     *full,
 			Pedantic: *pedantic,
 			Wait:     *wait,
+			Format:   chromium.Format(*format),
+			Quality:  *quality,
+			Tiled:    *tiled,
+			Block:    blocking,
+			Snapshot: *mhtml != "",
+			WARC:     *warc != "",
+		}
+		if !opts.Snapshot && !opts.WARC {
+			err = mediakit.CaptureScreenshot(nil, inURL, output, opts)
+			break
+		}
+		err = archive(inURL, output, opts)
+	case "pdf":
+		size, ok := papers[strings.ToLower(*paper)]
+		if !ok {
+			panic("unknown paper: " + *paper)
+		}
+		err = mediakit.CapturePDF(nil, inURL, output, chromium.PDFOptions{
+			Paper:          size,
+			Margins:        chromium.Margins{Top: *margin, Right: *margin, Bottom: *margin, Left: *margin},
+			Landscape:      *landscape,
+			HeaderTemplate: *header,
+			FooterTemplate: *footer,
+			Background:     *background,
+			PageRanges:     *pages,
+			Pedantic:       *pedantic,
+			Wait:           *wait,
+			Block:          blocking,
 		})
+	case "animation", "lottie":
+		err = animate(inURL, output)
 	default:
 		panic("unknown mode: " + *mode)
 	}
@@ -56,3 +127,71 @@ func main() {
 		panic(err)
 	}
 }
+
+func archive(url string, output *os.File, opts chromium.ScreenshotOptions) error {
+	// capture page
+	res, err := chromium.Capture(nil, url, opts)
+	if err != nil {
+		return err
+	}
+
+	// write image
+	_, err = output.Write(res.Image)
+	if err != nil {
+		return err
+	}
+
+	// write snapshot
+	if opts.Snapshot {
+		err = os.WriteFile(*mhtml, res.Snapshot, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// write records
+	if opts.WARC {
+		err = os.WriteFile(*warc, res.WARC, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func animate(input string, output *os.File) error {
+	// get preset
+	p := ffmpeg.ParsePreset(*preset)
+	if !p.Valid() {
+		panic("unknown preset: " + *preset)
+	}
+
+	// create temporary file
+	temp, err := os.CreateTemp("", "mk-capture-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	// prepare options
+	opts := chromium.AnimationOptions{
+		FrameRate: *fps,
+		Duration:  *duration,
+	}
+	if *mode == "animation" {
+		opts.Width = *width
+		opts.Height = *height
+		opts.Scale = *scale
+		return mediakit.RenderAnimation(nil, input, temp, output, opts, p)
+	}
+
+	// read animation
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	return mediakit.RenderLottie(nil, data, temp, output, opts, p)
+}
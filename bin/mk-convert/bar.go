@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const barWidth = 40
+
+type bar struct {
+	out      io.Writer
+	mutex    sync.Mutex
+	progress []float64
+	rendered time.Time
+}
+
+func newBar(out io.Writer, total int) *bar {
+	return &bar{
+		out:      out,
+		progress: make([]float64, total),
+	}
+}
+
+func (b *bar) update(i int, progress float64) {
+	// check bar
+	if b == nil {
+		return
+	}
+
+	// acquire mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// set progress
+	b.progress[i] = progress
+
+	// render at most ten times per second
+	if time.Since(b.rendered) >= 100*time.Millisecond || progress == 1 {
+		b.render()
+		b.rendered = time.Now()
+	}
+}
+
+func (b *bar) finish() {
+	// check bar
+	if b == nil {
+		return
+	}
+
+	// acquire mutex
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// render final state
+	b.render()
+	_, _ = fmt.Fprintln(b.out)
+}
+
+func (b *bar) render() {
+	// compute total progress
+	var sum float64
+	var done int
+	for _, p := range b.progress {
+		sum += p
+		if p >= 1 {
+			done++
+		}
+	}
+	total := 1.0
+	if len(b.progress) > 0 {
+		total = sum / float64(len(b.progress))
+	}
+
+	// render bar
+	filled := int(total * barWidth)
+	_, _ = fmt.Fprintf(b.out, "\r[%s%s] %3.0f%% (%d/%d)", strings.Repeat("#", filled), strings.Repeat(".", barWidth-filled), total*100, done, len(b.progress))
+}
@@ -1,89 +1,470 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/256dpi/xo"
-	"github.com/kr/pretty"
-
 	"github.com/256dpi/mediakit"
 	"github.com/256dpi/mediakit/ffmpeg"
 	"github.com/256dpi/mediakit/vips"
 )
 
-var mode = flag.String("mode", "video", "")
-var preset = flag.Int("preset", 0, "")
-var width = flag.Int("width", 300, "")
+var mode = flag.String("mode", "video", "the conversion mode: image, audio, video or extract")
+var preset = flag.String("preset", "", "the preset name or number, defaults to a mode specific preset")
+var width = flag.Int("width", 300, "the maximum width if no sizer is specified")
+var sizer = flag.String("sizer", "", "the sizer: keep, width:N, height:N, area:N or size:WxH")
+var frameRate = flag.Float64("frame-rate", 30, "the maximum frame rate")
+var sampleRate = flag.Int("sample-rate", 48000, "the maximum sample rate")
+var start = flag.Float64("start", 0, "the start of the converted segment in seconds (audio and video only)")
+var duration = flag.Float64("duration", 0, "the duration of the converted segment in seconds (audio and video only)")
+var position = flag.Float64("position", 0.25, "the relative position of the extracted image")
+var jobs = flag.Int("jobs", 1, "the number of parallel conversions in batch mode")
+var manifest = flag.String("manifest", "", "the path of the JSON manifest to write")
+var progress = flag.Bool("progress", true, "whether to show a progress bar")
+
+type task struct {
+	input  string
+	output string
+}
+
+type result struct {
+	Input   string  `json:"input"`
+	Output  string  `json:"output"`
+	Size    int64   `json:"size,omitempty"`
+	Elapsed float64 `json:"elapsed"`
+	Error   string  `json:"error,omitempty"`
+}
 
 func main() {
 	// parse flags
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: mk-convert [flags] <input> <output>")
+		fmt.Fprintln(os.Stderr, "       mk-convert [flags] <input-dir> <output-dir>")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 	if flag.NArg() != 2 {
-		panic("usage: mk-convert <input> <output>")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// prepare conversion
+	convert, ext, err := prepare()
+	if err != nil {
+		fatal(err)
 	}
 
 	// get paths
 	inPath, err := filepath.Abs(flag.Arg(0))
-	xo.PanicIf(err)
+	if err != nil {
+		fatal(err)
+	}
 	outPath, err := filepath.Abs(flag.Arg(1))
-	xo.PanicIf(err)
+	if err != nil {
+		fatal(err)
+	}
 
-	// open input
-	input, err := os.Open(inPath)
-	xo.PanicIf(err)
-	defer input.Close()
+	// collect tasks
+	tasks, skipped, err := collect(inPath, outPath, ext, mediaTypes())
+	if err != nil {
+		fatal(err)
+	}
 
-	// create temporary file
-	temporary, err := os.Create(outPath + ".tmp")
-	xo.PanicIf(err)
-	defer temporary.Close()
+	// report skipped files
+	for _, path := range skipped {
+		fmt.Fprintf(os.Stderr, "%s: skipped, unsupported media type for mode %s\n", path, *mode)
+	}
 
-	// create output
-	output, err := os.Create(outPath)
-	xo.PanicIf(err)
-	defer output.Close()
+	// prepare bar
+	var b *bar
+	if *progress {
+		b = newBar(os.Stderr, len(tasks))
+	}
 
-	// convert input
+	// run tasks
+	results := make([]result, len(tasks))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range max(*jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i] = execute(tasks[i], convert, func(p float64) {
+					b.update(i, p)
+				})
+				b.update(i, 1)
+			}
+		}()
+	}
+	for i := range tasks {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	b.finish()
+
+	// print errors
+	failed := false
+	for _, res := range results {
+		if res.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", res.Input, res.Error)
+			failed = true
+		}
+	}
+
+	// write manifest
+	if *manifest != "" {
+		buf, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		err = os.WriteFile(*manifest, append(buf, '\n'), 0644)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	// signal failure
+	if failed {
+		os.Exit(1)
+	}
+}
+
+type converter func(input, temp, output *os.File, progress *mediakit.Progress) error
+
+func prepare() (converter, string, error) {
+	// get sizer
+	sz, err := parseSizer(*sizer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// check segment
+	if (*mode == "image" || *mode == "extract") && (*start != 0 || *duration != 0) {
+		return nil, "", fmt.Errorf("-start and -duration are not supported in %s mode", *mode)
+	}
+
+	// prepare context
+	ctx := context.Background()
+
+	// prepare options
+	opts := mediakit.StreamOptions{
+		Segment: mediakit.Segment{
+			Start:    *start,
+			Duration: *duration,
+		},
+	}
+
+	// prepare converter
 	switch *mode {
-	case "image":
-		if *preset == 0 {
-			*preset = int(vips.JPGWeb)
+	case "image", "extract":
+		p, err := parseVipsPreset(*preset)
+		if err != nil {
+			return nil, "", err
 		}
-		err = mediakit.ConvertImage(nil, input, output, vips.Preset(*preset), mediakit.MaxWidth(*width))
+		ext := p.Arg()[:strings.Index(p.Arg(), "[")]
+		if *mode == "image" {
+			return func(input, _, output *os.File, _ *mediakit.Progress) error {
+				return mediakit.ConvertImage(ctx, input, output, p, sz)
+			}, ext, nil
+		}
+		return func(input, temp, output *os.File, _ *mediakit.Progress) error {
+			return mediakit.ExtractImage(ctx, input, temp, output, *position, p, sz)
+		}, ext, nil
 	case "audio":
-		if *preset == 0 {
-			*preset = int(ffmpeg.AudioMP3VBRStandard)
+		p, err := parseFFmpegPreset(*preset, ffmpeg.AudioMP3VBRStandard)
+		if err != nil {
+			return nil, "", err
 		}
-		err = mediakit.ConvertAudio(nil, input, output, ffmpeg.Preset(*preset), 48000, &mediakit.Progress{
-			Rate: time.Second,
-			Func: func(progress float64) {
-				pretty.Println(progress)
-			},
-		})
+		return func(input, _, output *os.File, progress *mediakit.Progress) error {
+			return mediakit.ConvertAudioWith(ctx, input, output, p, *sampleRate, progress, opts)
+		}, ffmpegExtensions[p], nil
 	case "video":
-		if *preset == 0 {
-			*preset = int(ffmpeg.VideoMP4H264AACFast)
+		p, err := parseFFmpegPreset(*preset, ffmpeg.VideoMP4H264AACFast)
+		if err != nil {
+			return nil, "", err
 		}
-		err = mediakit.ConvertVideo(nil, input, output, ffmpeg.Preset(*preset), mediakit.MaxWidth(*width), 30, 48000, &mediakit.Progress{
-			Rate: time.Second,
-			Func: func(progress float64) {
-				pretty.Println(progress)
-			},
-		})
-	case "extract":
-		if *preset == 0 {
-			*preset = int(vips.JPGWeb)
+		return func(input, _, output *os.File, progress *mediakit.Progress) error {
+			return mediakit.ConvertVideoWith(ctx, input, output, p, sz, *frameRate, *sampleRate, progress, opts)
+		}, ffmpegExtensions[p], nil
+	default:
+		return nil, "", fmt.Errorf("unknown mode: %s", *mode)
+	}
+}
+
+func mediaTypes() []string {
+	// get media types converted by mode
+	switch *mode {
+	case "image":
+		return mediakit.ImageTypes()
+	case "audio":
+		return slices.Concat(mediakit.AudioTypes(), mediakit.VideoTypes(), mediakit.ContainerTypes())
+	case "video":
+		return slices.Concat(mediakit.VideoTypes(), mediakit.ContainerTypes(), mediakit.AnimationTypes())
+	default:
+		return slices.Concat(mediakit.VideoTypes(), mediakit.ContainerTypes())
+	}
+}
+
+var ffmpegExtensions = map[ffmpeg.Preset]string{
+	ffmpeg.AudioMP3VBRStandard: ".mp3",
+	ffmpeg.VideoMP4H264AACFast: ".mp4",
+	ffmpeg.ImageJPEG:           ".jpg",
+	ffmpeg.ImagePNG:            ".png",
+	ffmpeg.ImageWebP:           ".webp",
+	ffmpeg.AnimationGIF:        ".gif",
+	ffmpeg.AnimationWebP:       ".webp",
+}
+
+func parseVipsPreset(str string) (vips.Preset, error) {
+	// handle default
+	if str == "" {
+		return vips.JPGWeb, nil
+	}
+
+	// parse preset
+	p := vips.ParsePreset(str)
+	if n, err := strconv.Atoi(str); err == nil {
+		p = vips.Preset(n)
+	}
+	if !p.Valid() {
+		return 0, fmt.Errorf("invalid preset: %s", str)
+	}
+
+	return p, nil
+}
+
+func parseFFmpegPreset(str string, def ffmpeg.Preset) (ffmpeg.Preset, error) {
+	// handle default
+	if str == "" {
+		return def, nil
+	}
+
+	// parse preset
+	p := ffmpeg.ParsePreset(str)
+	if n, err := strconv.Atoi(str); err == nil {
+		p = ffmpeg.Preset(n)
+	}
+	if !p.Valid() {
+		return 0, fmt.Errorf("invalid preset: %s", str)
+	}
+
+	return p, nil
+}
+
+func parseSizer(str string) (mediakit.Sizer, error) {
+	// handle default
+	if str == "" {
+		return mediakit.MaxWidth(*width), nil
+	} else if str == "keep" {
+		return mediakit.KeepSize(), nil
+	}
+
+	// split sizer
+	kind, value, _ := strings.Cut(str, ":")
+
+	// handle size
+	if kind == "size" {
+		w, h, _ := strings.Cut(value, "x")
+		wn, err1 := strconv.Atoi(w)
+		hn, err2 := strconv.Atoi(h)
+		if err1 != nil || err2 != nil || wn <= 0 || hn <= 0 {
+			return nil, fmt.Errorf("invalid sizer: %s", str)
 		}
-		err = mediakit.ExtractImage(nil, input, temporary, output, 0.25, vips.Preset(*preset), mediakit.MaxWidth(*width))
+		return mediakit.MaxSize(mediakit.Size{Width: wn, Height: hn}), nil
+	}
+
+	// parse value
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid sizer: %s", str)
+	}
+
+	switch kind {
+	case "width":
+		return mediakit.MaxWidth(n), nil
+	case "height":
+		return mediakit.MaxHeight(n), nil
+	case "area":
+		return mediakit.MaxArea(n), nil
 	default:
-		panic("unknown mode: " + *mode)
+		return nil, fmt.Errorf("invalid sizer: %s", str)
 	}
-	xo.PanicIf(err)
+}
+
+func collect(inPath, outPath, ext string, types []string) ([]task, []string, error) {
+	// get info
+	info, err := os.Stat(inPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// handle files
+	if !info.IsDir() {
+		return []task{{input: inPath, output: outPath}}, nil, nil
+	}
+
+	// check output
+	if outPath == inPath {
+		return nil, nil, fmt.Errorf("output directory must differ from input directory")
+	}
+
+	// walk directory
+	var tasks []task
+	var skipped []string
+	inputs := map[string]string{}
+	err = filepath.WalkDir(inPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// skip output directory if nested
+		if entry.IsDir() && path == outPath {
+			return filepath.SkipDir
+		}
+
+		// skip directories and special files
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		// skip unsupported files
+		typ, err := detect(path)
+		if err != nil {
+			return err
+		} else if !slices.Contains(types, typ) {
+			skipped = append(skipped, path)
+			return nil
+		}
+
+		// get relative path
+		rel, err := filepath.Rel(inPath, path)
+		if err != nil {
+			return err
+		}
+
+		// get output
+		output := filepath.Join(outPath, strings.TrimSuffix(rel, filepath.Ext(rel))+ext)
+
+		// check conflicts
+		if other, ok := inputs[output]; ok {
+			return fmt.Errorf("%s and %s convert to the same output %s", other, path, output)
+		}
+		inputs[output] = path
+
+		// add task
+		tasks = append(tasks, task{
+			input:  path,
+			output: output,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tasks, skipped, nil
+}
+
+func detect(path string) (string, error) {
+	// open file
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// detect type
+	typ, _, err := mediakit.DetectStream(file, false)
+	if err != nil {
+		return "", err
+	}
+
+	return typ, nil
+}
+
+func execute(t task, convert converter, fn func(float64)) result {
+	// prepare result
+	res := result{
+		Input:  t.input,
+		Output: t.output,
+	}
+
+	// run conversion
+	started := time.Now()
+	err := run(t, convert, fn)
+	res.Elapsed = time.Since(started).Seconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	// get size
+	info, err := os.Stat(t.output)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Size = info.Size()
+
+	return res
+}
+
+func run(t task, convert converter, fn func(float64)) error {
+	// ensure directory
+	err := os.MkdirAll(filepath.Dir(t.output), 0755)
+	if err != nil {
+		return err
+	}
+
+	// open input
+	input, err := os.Open(t.input)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// create temporary file
+	temporary, err := os.Create(t.output + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	defer temporary.Close()
+
+	// create output
+	output, err := os.Create(t.output)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	// convert input
+	err = convert(input, temporary, output, &mediakit.Progress{
+		Rate: time.Second / 4,
+		Func: fn,
+	})
+	if err != nil {
+		_ = os.Remove(t.output)
+		return err
+	}
+
+	return nil
+}
 
-	// remove temporary
-	err = os.Remove(outPath + ".tmp")
-	xo.PanicIf(err)
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "mk-convert: %s\n", err.Error())
+	os.Exit(2)
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/chromium"
+)
+
+const (
+	statusQueued  = "queued"
+	statusRunning = "running"
+	statusDone    = "done"
+	statusFailed  = "failed"
+)
+
+type state struct {
+	ID       string           `json:"id"`
+	Kind     string           `json:"kind"`
+	Status   string           `json:"status"`
+	Progress float64          `json:"progress"`
+	Error    string           `json:"error,omitempty"`
+	Report   *mediakit.Report `json:"report,omitempty"`
+	Type     string           `json:"type,omitempty"`
+}
+
+type job struct {
+	state    state
+	output   string
+	webhook  string
+	files    []string
+	cancel   context.CancelFunc
+	mutex    sync.Mutex
+	done     chan struct{}
+	watchers map[chan state]bool
+}
+
+func newJob(kind, webhook string) *job {
+	// generate id
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return &job{
+		state: state{
+			ID:     hex.EncodeToString(buf),
+			Kind:   kind,
+			Status: statusQueued,
+		},
+		webhook:  webhook,
+		done:     make(chan struct{}),
+		watchers: map[chan state]bool{},
+	}
+}
+
+func (j *job) get() state {
+	// acquire mutex
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.state
+}
+
+func (j *job) update(fn func(s *state)) {
+	// acquire mutex
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	// apply update
+	fn(&j.state)
+
+	// notify watchers, dropping updates for slow watchers
+	for ch := range j.watchers {
+		select {
+		case ch <- j.state:
+		default:
+		}
+	}
+}
+
+func (j *job) watch() (chan state, func()) {
+	// acquire mutex
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	// add watcher
+	ch := make(chan state, 16)
+	j.watchers[ch] = true
+
+	return ch, func() {
+		j.mutex.Lock()
+		delete(j.watchers, ch)
+		j.mutex.Unlock()
+	}
+}
+
+func (j *job) start() {
+	// set status, jobs using multiple tools may start repeatedly
+	j.update(func(s *state) {
+		s.Status = statusRunning
+	})
+}
+
+func (j *job) run(ctx context.Context, fn func(context.Context, func(float64)) error) {
+	// run job, the status is set to running once a pool slot is acquired
+	err := fn(ctx, func(progress float64) {
+		j.update(func(s *state) {
+			s.Progress = progress
+		})
+	})
+
+	// set result
+	j.update(func(s *state) {
+		if err != nil {
+			s.Status = statusFailed
+			s.Error = err.Error()
+		} else {
+			s.Status = statusDone
+			s.Progress = 1
+		}
+	})
+	close(j.done)
+
+	// call webhook
+	if j.webhook != "" {
+		go notify(j.webhook, j.get())
+	}
+}
+
+func (j *job) cleanup() {
+	// remove files
+	for _, file := range j.files {
+		_ = os.Remove(file)
+	}
+}
+
+func webhookClient() *http.Client {
+	// prepare dialer
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	// refuse connections to non-public addresses, the check is applied to
+	// the dialed address to cover redirects and DNS rebinding
+	if !*allowPrivate {
+		guard := &chromium.Guard{}
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			} else if !guard.Allowed(addr.Addr()) {
+				return fmt.Errorf("address %s not allowed", addr.Addr())
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+		},
+	}
+}
+
+func notify(url string, s state) {
+	// encode state
+	buf, err := json.Marshal(s)
+	if err != nil {
+		log.Printf("webhook: %s", err.Error())
+		return
+	}
+
+	// post state
+	res, err := webhookClient().Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		log.Printf("webhook: %s", err.Error())
+		return
+	}
+	_ = res.Body.Close()
+}
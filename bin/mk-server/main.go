@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/chromium"
+)
+
+var addr = flag.String("addr", ":8080", "the address to listen on")
+var dir = flag.String("dir", os.TempDir(), "the directory for uploads and results")
+var root = flag.String("root", "", "the directory local paths are resolved in, disabled if empty")
+var ttl = flag.Duration("ttl", time.Hour, "the time jobs and results are kept")
+var timeout = flag.Duration("timeout", 10*time.Minute, "the maximum time a job may take including queueing, disabled if zero")
+var maxUpload = flag.Int64("max-upload", 1<<30, "the maximum upload size in bytes")
+var ffmpegJobs = flag.Int("ffmpeg", 2, "the number of concurrent ffmpeg jobs")
+var vipsJobs = flag.Int("vips", 4, "the number of concurrent vips jobs")
+var chromiumJobs = flag.Int("chromium", 2, "the number of concurrent chromium jobs")
+var allowPrivate = flag.Bool("allow-private", false, "whether captures and webhooks may access private networks")
+
+func main() {
+	// parse flags
+	flag.Parse()
+
+	// create server
+	srv := newServer(mediakit.NewPool(mediakit.PoolConfig{
+		FFmpeg:   *ffmpegJobs,
+		Vips:     *vipsJobs,
+		Chromium: *chromiumJobs,
+		Browsers: chromium.NewPool(chromium.PoolConfig{
+			Size:    *chromiumJobs,
+			MaxUses: 100,
+			Timeout: time.Minute,
+		}),
+	}), *timeout)
+
+	// prepare routes
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /analyze", srv.handle(kindAnalyze))
+	mux.HandleFunc("POST /convert", srv.handle(kindConvert))
+	mux.HandleFunc("POST /extract", srv.handle(kindExtract))
+	mux.HandleFunc("POST /capture", srv.handle(kindCapture))
+	mux.HandleFunc("GET /jobs/{id}", srv.status)
+	mux.HandleFunc("DELETE /jobs/{id}", srv.abort)
+	mux.HandleFunc("GET /jobs/{id}/events", srv.events)
+	mux.HandleFunc("GET /jobs/{id}/result", srv.result)
+
+	// await signals
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// run server
+	httpServer := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		log.Printf("listening on %s", *addr)
+		err := httpServer.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// cancel jobs and shut down server
+	<-ctx.Done()
+	log.Printf("shutting down")
+	srv.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := httpServer.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
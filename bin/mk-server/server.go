@@ -0,0 +1,564 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/chromium"
+	"github.com/256dpi/mediakit/ffmpeg"
+	"github.com/256dpi/mediakit/vips"
+)
+
+const (
+	kindAnalyze = "analyze"
+	kindConvert = "convert"
+	kindExtract = "extract"
+	kindCapture = "capture"
+)
+
+type server struct {
+	pool    *mediakit.Pool
+	timeout time.Duration
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mutex   sync.Mutex
+	jobs    map[string]*job
+}
+
+func newServer(pool *mediakit.Pool, timeout time.Duration) *server {
+	// prepare context
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &server{
+		pool:    pool,
+		timeout: timeout,
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    map[string]*job{},
+	}
+}
+
+// Close will cancel all running and queued jobs.
+func (s *server) Close() {
+	s.cancel()
+}
+
+func (s *server) context(parent context.Context) (context.Context, context.CancelFunc) {
+	// apply timeout
+	ctx, cancel := parent, context.CancelFunc(func() {})
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, s.timeout)
+	}
+
+	// cancel with server
+	stop := context.AfterFunc(s.ctx, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (s *server) handle(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// parse form
+		r.Body = http.MaxBytesReader(w, r.Body, *maxUpload)
+		err := r.ParseMultipartForm(32 << 20)
+		if err != nil && err != http.ErrNotMultipart {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		} else if err == http.ErrNotMultipart {
+			err = r.ParseForm()
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		// check webhook
+		webhook := r.FormValue("webhook")
+		if webhook != "" && !strings.HasPrefix(webhook, "http://") && !strings.HasPrefix(webhook, "https://") {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid webhook"))
+			return
+		}
+
+		// create job
+		j := newJob(kind, webhook)
+
+		// get input
+		var input string
+		if kind != kindCapture {
+			input, err = s.input(r, j)
+			if err != nil {
+				j.cleanup()
+				writeError(w, errorStatus(err), err)
+				return
+			}
+		}
+
+		// prepare work
+		work, err := s.work(kind, r, j, input)
+		if err != nil {
+			j.cleanup()
+			writeError(w, errorStatus(err), err)
+			return
+		}
+
+		// prepare context, asynchronous jobs are detached from the request
+		async := formBool(r, "async")
+		parent := r.Context()
+		if async {
+			parent = context.Background()
+		}
+		ctx, cancel := s.context(parent)
+		j.cancel = cancel
+
+		// register job
+		s.mutex.Lock()
+		s.jobs[j.state.ID] = j
+		s.mutex.Unlock()
+
+		// schedule removal
+		time.AfterFunc(*ttl, func() {
+			<-j.done
+			s.mutex.Lock()
+			delete(s.jobs, j.state.ID)
+			s.mutex.Unlock()
+			j.cleanup()
+		})
+
+		// run asynchronous jobs in the background
+		if async {
+			go func() {
+				defer cancel()
+				j.run(ctx, work)
+			}()
+			w.Header().Set("Location", "/jobs/"+j.state.ID)
+			writeJSON(w, http.StatusAccepted, j.get())
+			return
+		}
+
+		// otherwise, run job and write result
+		j.run(ctx, work)
+		cancel()
+		s.write(w, r, j)
+	}
+}
+
+func (s *server) status(w http.ResponseWriter, r *http.Request) {
+	// get job
+	j := s.get(w, r)
+	if j == nil {
+		return
+	}
+
+	// write state
+	writeJSON(w, http.StatusOK, j.get())
+}
+
+func (s *server) abort(w http.ResponseWriter, r *http.Request) {
+	// get job
+	j := s.get(w, r)
+	if j == nil {
+		return
+	}
+
+	// cancel job
+	j.cancel()
+
+	writeJSON(w, http.StatusAccepted, j.get())
+}
+
+func (s *server) events(w http.ResponseWriter, r *http.Request) {
+	// get job
+	j := s.get(w, r)
+	if j == nil {
+		return
+	}
+
+	// get flusher
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	// watch job
+	ch, cancel := j.watch()
+	defer cancel()
+
+	// write header
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// stream states until the job is done
+	st := j.get()
+	for {
+		// write event
+		buf, _ := json.Marshal(st)
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", st.Status, buf)
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+
+		// check status
+		if st.Status == statusDone || st.Status == statusFailed {
+			return
+		}
+
+		// await next state
+		select {
+		case st = <-ch:
+		case <-j.done:
+			st = j.get()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *server) result(w http.ResponseWriter, r *http.Request) {
+	// get job
+	j := s.get(w, r)
+	if j == nil {
+		return
+	}
+
+	// check status
+	select {
+	case <-j.done:
+	default:
+		writeJSON(w, http.StatusConflict, j.get())
+		return
+	}
+
+	// write result
+	s.write(w, r, j)
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) *job {
+	// get job
+	s.mutex.Lock()
+	j := s.jobs[r.PathValue("id")]
+	s.mutex.Unlock()
+	if j == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job not found"))
+		return nil
+	}
+
+	return j
+}
+
+func (s *server) write(w http.ResponseWriter, r *http.Request, j *job) {
+	// get state
+	st := j.get()
+
+	// handle failure
+	if st.Status == statusFailed {
+		writeJSON(w, http.StatusUnprocessableEntity, st)
+		return
+	}
+
+	// handle report
+	if st.Report != nil {
+		writeJSON(w, http.StatusOK, st.Report)
+		return
+	}
+
+	// write file
+	w.Header().Set("Content-Type", st.Type)
+	http.ServeFile(w, r, j.output)
+}
+
+func (s *server) input(r *http.Request, j *job) (string, error) {
+	// handle upload
+	file, _, err := r.FormFile("file")
+	if err == nil {
+		defer file.Close()
+
+		// create file
+		input, err := os.CreateTemp(*dir, "mk-input-")
+		if err != nil {
+			return "", err
+		}
+		defer input.Close()
+		j.files = append(j.files, input.Name())
+
+		// copy upload
+		_, err = io.Copy(input, file)
+		if err != nil {
+			return "", err
+		}
+
+		return input.Name(), nil
+	}
+
+	// get path
+	path := r.FormValue("path")
+	if path == "" {
+		return "", invalid("missing file or path")
+	} else if *root == "" {
+		return "", invalid("local paths are disabled")
+	}
+
+	// resolve root
+	rootPath, err := filepath.EvalSymlinks(*root)
+	if err != nil {
+		return "", err
+	}
+
+	// resolve path within root, including symlinks
+	path, err = filepath.EvalSymlinks(filepath.Join(rootPath, filepath.Clean("/"+path)))
+	if err != nil {
+		return "", invalid("path not found")
+	}
+
+	// check path
+	rel, err := filepath.Rel(rootPath, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", invalid("path outside root")
+	}
+
+	return path, nil
+}
+
+func (s *server) work(kind string, r *http.Request, j *job, input string) (func(context.Context, func(float64)) error, error) {
+	// prepare output
+	var output string
+	if kind != kindAnalyze {
+		output = filepath.Join(*dir, "mk-output-"+j.state.ID)
+		j.output = output
+		j.files = append(j.files, output, output+".tmp")
+	}
+
+	// get pool, marking the job running once a slot is acquired
+	pool := s.pool.With(mediakit.JobOptions{
+		Started: j.start,
+	})
+
+	// get sizer
+	sizer := mediakit.KeepSize()
+	if width := formInt(r, "width", 0); width > 0 {
+		sizer = mediakit.MaxWidth(width)
+	}
+
+	// get common options
+	preset := formInt(r, "preset", 0)
+	maxFrameRate := formFloat(r, "frame-rate", 30)
+	maxSampleRate := formInt(r, "sample-rate", 48000)
+
+	switch kind {
+	case kindAnalyze:
+		return func(ctx context.Context, _ func(float64)) error {
+			return withFiles(input, "", func(in, _ *os.File) error {
+				report, err := pool.Analyze(ctx, in)
+				if err != nil {
+					return err
+				}
+				j.update(func(s *state) {
+					s.Report = report
+				})
+				return nil
+			})
+		}, nil
+	case kindConvert:
+		mode := r.FormValue("mode")
+		switch mode {
+		case "image":
+			if preset == 0 {
+				preset = int(vips.JPGWeb)
+			}
+			if !vips.Preset(preset).Valid() {
+				return nil, invalid("invalid preset")
+			}
+			return s.convert(j, input, output, func(ctx context.Context, in, out *os.File, _ *mediakit.Progress) error {
+				return pool.ConvertImage(ctx, in, out, vips.Preset(preset), sizer)
+			}), nil
+		case "audio":
+			if preset == 0 {
+				preset = int(ffmpeg.AudioMP3VBRStandard)
+			}
+			if !ffmpeg.Preset(preset).Valid() {
+				return nil, invalid("invalid preset")
+			}
+			return s.convert(j, input, output, func(ctx context.Context, in, out *os.File, progress *mediakit.Progress) error {
+				return pool.ConvertAudio(ctx, in, out, ffmpeg.Preset(preset), maxSampleRate, progress)
+			}), nil
+		case "video", "":
+			if preset == 0 {
+				preset = int(ffmpeg.VideoMP4H264AACFast)
+			}
+			if !ffmpeg.Preset(preset).Valid() {
+				return nil, invalid("invalid preset")
+			}
+			return s.convert(j, input, output, func(ctx context.Context, in, out *os.File, progress *mediakit.Progress) error {
+				return pool.ConvertVideo(ctx, in, out, ffmpeg.Preset(preset), sizer, maxFrameRate, maxSampleRate, progress)
+			}), nil
+		default:
+			return nil, invalid("unknown mode: %s", mode)
+		}
+	case kindExtract:
+		if preset == 0 {
+			preset = int(vips.JPGWeb)
+		}
+		if !vips.Preset(preset).Valid() {
+			return nil, invalid("invalid preset")
+		}
+		position := formFloat(r, "position", 0.25)
+		return s.convert(j, input, output, func(ctx context.Context, in, out *os.File, _ *mediakit.Progress) error {
+			temp, err := os.Create(output + ".tmp")
+			if err != nil {
+				return err
+			}
+			defer temp.Close()
+			return pool.ExtractImage(ctx, in, temp, out, position, vips.Preset(preset), sizer)
+		}), nil
+	case kindCapture:
+		url := r.FormValue("url")
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, invalid("invalid url")
+		}
+		opts := chromium.ScreenshotOptions{
+			Width:  int64(formInt(r, "width", 1920)),
+			Height: int64(formInt(r, "height", 1080)),
+			Scale:  formFloat(r, "scale", 1),
+			Full:   formBool(r, "full"),
+			Wait:   formDuration(r, "wait", 0),
+		}
+		if !*allowPrivate {
+			opts.Guard = &chromium.Guard{}
+		}
+		return s.convert(j, "", output, func(ctx context.Context, _, out *os.File, _ *mediakit.Progress) error {
+			return pool.CaptureScreenshot(ctx, url, out, opts)
+		}), nil
+	default:
+		return nil, invalid("unknown kind: %s", kind)
+	}
+}
+
+func (s *server) convert(j *job, input, output string, fn func(ctx context.Context, in, out *os.File, progress *mediakit.Progress) error) func(context.Context, func(float64)) error {
+	return func(ctx context.Context, progress func(float64)) error {
+		return withFiles(input, output, func(in, out *os.File) error {
+			// run conversion
+			err := fn(ctx, in, out, &mediakit.Progress{
+				Rate: time.Second,
+				Func: progress,
+			})
+			if err != nil {
+				return err
+			}
+
+			// rewind output
+			_, err = out.Seek(0, io.SeekStart)
+			if err != nil {
+				return err
+			}
+
+			// detect type
+			buf := make([]byte, mediakit.DetectBytes)
+			n, _ := io.ReadFull(out, buf)
+			j.update(func(s *state) {
+				s.Type = mediakit.Detect(buf[:n], false)
+			})
+
+			return nil
+		})
+	}
+}
+
+func withFiles(input, output string, fn func(in, out *os.File) error) error {
+	// open input
+	var in *os.File
+	if input != "" {
+		var err error
+		in, err = os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+
+	// create output
+	var out *os.File
+	if output != "" {
+		var err error
+		out, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	return fn(in, out)
+}
+
+func formInt(r *http.Request, name string, def int) int {
+	n, err := strconv.Atoi(r.FormValue(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+func formFloat(r *http.Request, name string, def float64) float64 {
+	f, err := strconv.ParseFloat(r.FormValue(name), 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+func formBool(r *http.Request, name string) bool {
+	b, _ := strconv.ParseBool(r.FormValue(name))
+	return b
+}
+
+func formDuration(r *http.Request, name string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(r.FormValue(name))
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+type requestError struct {
+	error
+}
+
+func invalid(format string, args ...any) error {
+	return requestError{fmt.Errorf(format, args...)}
+}
+
+func errorStatus(err error) int {
+	// check request error
+	if errors.As(err, &requestError{}) {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
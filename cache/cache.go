@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a key value store used to cache values.
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte) error
+}
+
+// Hash returns the hex encoded SHA-256 hash of the full input. The input is
+// rewound to the original position afterward.
+func Hash(r io.ReadSeeker) (string, error) {
+	// get position
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+
+	// seek start
+	_, err = r.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	// hash input
+	hash := sha256.New()
+	_, err = io.Copy(hash, r)
+	if err != nil {
+		return "", err
+	}
+
+	// rewind input
+	_, err = r.Seek(pos, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Lookup will compute the key for the specified input and load a cached value
+// into the provided value. If the input is not seekable (e.g. a pipe), an empty
+// key is returned and the cache is skipped.
+func Lookup(store Store, prefix string, r io.Reader, value any) (string, bool, error) {
+	// check input
+	seeker, ok := r.(io.ReadSeeker)
+	if !ok {
+		return "", false, nil
+	}
+
+	// check seeking, which fails for pipes wrapped in files
+	_, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", false, nil
+	}
+
+	// compute key
+	hash, err := Hash(seeker)
+	if err != nil {
+		return "", false, err
+	}
+	key := prefix + hash
+
+	// get value
+	buf, ok, err := store.Get(key)
+	if err != nil || !ok {
+		return key, false, err
+	}
+
+	// decode value
+	err = json.Unmarshal(buf, value)
+	if err != nil {
+		return key, false, err
+	}
+
+	return key, true, nil
+}
+
+// Save will encode and store the provided value.
+func Save(store Store, key string, value any) error {
+	// encode value
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return store.Set(key, buf)
+}
+
+// Memory is an in-memory store that evicts the least recently used values.
+type Memory struct {
+	size  int
+	mutex sync.Mutex
+	list  *list.List
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemory creates and returns a new memory store that holds up to the
+// specified number of values.
+func NewMemory(size int) *Memory {
+	return &Memory{
+		size:  size,
+		list:  list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// Get implements the Store interface.
+func (m *Memory) Get(key string) ([]byte, bool, error) {
+	// acquire mutex
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// get item
+	elem, ok := m.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	// mark used
+	m.list.MoveToFront(elem)
+
+	return elem.Value.(*memoryItem).value, true, nil
+}
+
+// Set implements the Store interface.
+func (m *Memory) Set(key string, value []byte) error {
+	// acquire mutex
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// update existing item
+	if elem, ok := m.items[key]; ok {
+		elem.Value.(*memoryItem).value = value
+		m.list.MoveToFront(elem)
+		return nil
+	}
+
+	// add item
+	m.items[key] = m.list.PushFront(&memoryItem{key: key, value: value})
+
+	// evict least recently used items
+	for m.list.Len() > m.size {
+		elem := m.list.Back()
+		m.list.Remove(elem)
+		delete(m.items, elem.Value.(*memoryItem).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of stored values.
+func (m *Memory) Len() int {
+	// acquire mutex
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.list.Len()
+}
+
+// Disk is a store that keeps values as files in a directory.
+type Disk struct {
+	dir string
+}
+
+// NewDisk creates and returns a new disk store using the specified directory.
+// The directory is created if missing.
+func NewDisk(dir string) (*Disk, error) {
+	// ensure directory
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Disk{dir: dir}, nil
+}
+
+// Get implements the Store interface.
+func (d *Disk) Get(key string) ([]byte, bool, error) {
+	// read file
+	buf, err := os.ReadFile(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return buf, true, nil
+}
+
+// Set implements the Store interface.
+func (d *Disk) Set(key string, value []byte) error {
+	// write temporary file
+	file, err := os.CreateTemp(d.dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(value)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	// move file into place
+	err = os.Rename(file.Name(), d.path(key))
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (d *Disk) path(key string) string {
+	// hash key to get a safe file name
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	r := strings.NewReader("Hello World!")
+
+	hash, err := Hash(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069", hash)
+	assert.Equal(t, 12, r.Len())
+
+	_, err = r.Seek(6, io.SeekStart)
+	assert.NoError(t, err)
+
+	hash, err = Hash(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069", hash)
+	assert.Equal(t, 6, r.Len())
+}
+
+func TestMemory(t *testing.T) {
+	store := NewMemory(2)
+
+	value, ok, err := store.Get("a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, value)
+
+	assert.NoError(t, store.Set("a", []byte("1")))
+	assert.NoError(t, store.Set("b", []byte("2")))
+
+	value, ok, err = store.Get("a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	assert.NoError(t, store.Set("c", []byte("3")))
+	assert.Equal(t, 2, store.Len())
+
+	_, ok, _ = store.Get("b")
+	assert.False(t, ok)
+	_, ok, _ = store.Get("a")
+	assert.True(t, ok)
+	_, ok, _ = store.Get("c")
+	assert.True(t, ok)
+}
+
+func TestDisk(t *testing.T) {
+	store, err := NewDisk(t.TempDir())
+	assert.NoError(t, err)
+
+	value, ok, err := store.Get("ffmpeg:abc")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, value)
+
+	assert.NoError(t, store.Set("ffmpeg:abc", []byte("1")))
+	assert.NoError(t, store.Set("ffmpeg:abc", []byte("2")))
+
+	value, ok, err = store.Get("ffmpeg:abc")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("2"), value)
+}
+
+func TestLookupSave(t *testing.T) {
+	store := NewMemory(10)
+
+	var value map[string]int
+	key, ok, err := Lookup(store, "test:", strings.NewReader("foo"), &value)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "test:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", key)
+
+	err = Save(store, key, map[string]int{"foo": 42})
+	assert.NoError(t, err)
+
+	key, ok, err = Lookup(store, "test:", strings.NewReader("foo"), &value)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]int{"foo": 42}, value)
+
+	key, ok, err = Lookup(store, "test:", io.MultiReader(strings.NewReader("foo")), &value)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, key)
+
+	pr, pw, err := os.Pipe()
+	assert.NoError(t, err)
+	defer pr.Close()
+	defer pw.Close()
+
+	key, ok, err = Lookup(store, "test:", pr, &value)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, key)
+}
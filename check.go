@@ -0,0 +1,100 @@
+package mediakit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/256dpi/xo"
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/ffmpeg"
+	"github.com/256dpi/mediakit/vips"
+)
+
+var imageLoaders = map[string]string{
+	"image/gif":       "gifload",
+	"image/heic":      "heifload",
+	"image/heif":      "heifload",
+	"image/jpeg":      "jpegload",
+	"image/jp2":       "jp2kload",
+	"application/pdf": "pdfload",
+	"image/png":       "pngload",
+	"image/tiff":      "tiffload",
+	"image/webp":      "webpload",
+}
+
+var mediaDemuxers = map[string]string{
+	"audio/aac":        "aac",
+	"audio/aiff":       "aiff",
+	"audio/flac":       "flac",
+	"audio/x-m4a":      "mov",
+	"audio/mpeg":       "mp3",
+	"audio/ogg":        "ogg",
+	"audio/wav":        "wav",
+	"video/x-msvideo":  "avi",
+	"video/x-flv":      "flv",
+	"video/x-matroska": "matroska",
+	"video/quicktime":  "mov",
+	"video/mpeg":       "mpeg",
+	"video/mp4":        "mov",
+	"video/ogg":        "ogg",
+	"video/webm":       "webm",
+	"video/x-ms-asf":   "asf",
+}
+
+// ErrUnsupported is returned by Check if the local tools do not support all
+// presets and media types.
+var ErrUnsupported = xo.BF("unsupported")
+
+// Check will verify that the locally installed ffmpeg and vips utilities
+// support all presets and the media types listed by ImageTypes, AudioTypes,
+// VideoTypes and ContainerTypes.
+func Check(ctx context.Context) error {
+	// get capabilities
+	ffmpegFeatures, err := ffmpeg.Capabilities(ctx)
+	if err != nil {
+		return xo.W(err)
+	}
+	vipsFeatures, err := vips.Capabilities(ctx)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// check ffmpeg presets
+	var problems []string
+	for preset := ffmpeg.Preset(1); preset.Valid(); preset++ {
+		err = ffmpegFeatures.Supports(preset)
+		if err != nil {
+			problems = append(problems, "ffmpeg preset "+preset.String()+": "+err.Error())
+		}
+	}
+
+	// check vips presets
+	for preset := vips.Preset(1); preset.Valid(); preset++ {
+		err = vipsFeatures.Supports(preset)
+		if err != nil {
+			problems = append(problems, "vips preset "+preset.String()+": "+err.Error())
+		}
+	}
+
+	// check image types
+	for _, typ := range ImageTypes() {
+		if !lo.Contains(vipsFeatures.Loaders, imageLoaders[typ]) {
+			problems = append(problems, typ+": missing loader "+imageLoaders[typ])
+		}
+	}
+
+	// check audio, video and container types
+	for _, typ := range lo.Flatten([][]string{AudioTypes(), VideoTypes(), ContainerTypes()}) {
+		if !lo.Contains(ffmpegFeatures.Demuxers, mediaDemuxers[typ]) {
+			problems = append(problems, typ+": missing demuxer "+mediaDemuxers[typ])
+		}
+	}
+
+	// check problems
+	if len(problems) > 0 {
+		return ErrUnsupported.WrapF("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
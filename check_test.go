@@ -0,0 +1,12 @@
+package mediakit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	err := Check(nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,289 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"math"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+//go:embed lottie
+var lottieFiles embed.FS
+
+var lottiePlayer = template.Must(template.ParseFS(lottieFiles, "lottie/player.html"))
+
+// AnimationOptions are the options used for rendering animations.
+type AnimationOptions struct {
+	// The viewport size and scale.
+	Width  int64
+	Height int64
+	Scale  float64
+
+	// The frame rate, defaults to 30.
+	FrameRate float64
+
+	// The duration of the animation.
+	Duration time.Duration
+
+	// The virtual time granted to load the page before the first frame,
+	// defaults to five seconds.
+	LoadTime time.Duration
+
+	// The CSS background of the Lottie player page, defaults to white.
+	Background string
+
+	// The requests to block. AnimateHTML and AnimateLottie block requests to
+	// other origins by default, an empty Blocking allows all requests.
+	Block *Blocking
+}
+
+// Animate will load the given URL and step it through virtual time at the
+// configured frame rate. A PNG image is captured per frame and written to
+// the writer, which can be encoded using ffmpeg.Convert with the
+// "image2pipe" input format. A browser context may be provided using
+// Allocate, otherwise a new one will be allocated.
+func Animate(ctx context.Context, url string, w io.Writer, opts AnimationOptions) error {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	return animate(ctx, url, w, opts, nil)
+}
+
+// AnimateHTML will render the given HTML document like RenderHTML and capture
+// its frames like Animate. Requests to other origins are blocked unless the
+// options configure their own blocking.
+func AnimateHTML(ctx context.Context, html string, assets fs.FS, w io.Writer, opts AnimationOptions) error {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	// prepare interceptor
+	ic := &interceptor{
+		origin: RenderOrigin,
+		html:   []byte(html),
+		assets: assets,
+	}
+
+	// block other origins by default
+	if opts.Block == nil {
+		opts.Block = &Blocking{Offline: true}
+	}
+
+	return animate(ctx, RenderOrigin+"/", w, opts, ic)
+}
+
+// LottieOptions will return the options with the size, frame rate and
+// duration defaulting to the ones of the provided Lottie animation.
+func LottieOptions(data []byte, opts AnimationOptions) (AnimationOptions, error) {
+	// parse animation
+	var info struct {
+		FrameRate float64 `json:"fr"`
+		In        float64 `json:"ip"`
+		Out       float64 `json:"op"`
+		Width     int64   `json:"w"`
+		Height    int64   `json:"h"`
+	}
+	err := json.Unmarshal(data, &info)
+	if err != nil {
+		return opts, fmt.Errorf("invalid animation: %w", err)
+	}
+
+	// apply defaults
+	if opts.Width == 0 && opts.Height == 0 {
+		opts.Width = info.Width
+		opts.Height = info.Height
+	}
+	if opts.FrameRate == 0 {
+		opts.FrameRate = info.FrameRate
+	}
+	if opts.Duration == 0 && info.FrameRate > 0 {
+		opts.Duration = time.Duration((info.Out - info.In) / info.FrameRate * float64(time.Second))
+	}
+
+	return opts, nil
+}
+
+// AnimateLottie will play the given Lottie animation using the bundled
+// lottie-web player and capture its frames like Animate. The options default
+// to the animation using LottieOptions.
+func AnimateLottie(ctx context.Context, data []byte, w io.Writer, opts AnimationOptions) error {
+	// apply defaults
+	opts, err := LottieOptions(data, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Background == "" {
+		opts.Background = "white"
+	}
+
+	// get player assets
+	assets, err := fs.Sub(lottieFiles, "lottie")
+	if err != nil {
+		return err
+	}
+
+	// check script
+	_, err = fs.Stat(assets, "lottie.min.js")
+	if err != nil {
+		return fmt.Errorf("lottie player script not bundled, run \"make lottie\"")
+	}
+
+	// render player
+	html, err := lottiePage(data, opts.Background)
+	if err != nil {
+		return err
+	}
+
+	return AnimateHTML(ctx, html, assets, w, opts)
+}
+
+func lottiePage(data []byte, background string) (string, error) {
+	// escape animation for the script context
+	var animation bytes.Buffer
+	json.HTMLEscape(&animation, data)
+
+	// render page
+	var buf bytes.Buffer
+	err := lottiePlayer.Execute(&buf, map[string]any{
+		"Background": background,
+		"Data":       template.JS(animation.String()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func animate(ctx context.Context, url string, w io.Writer, opts AnimationOptions, ic *interceptor) error {
+	// get frame rate
+	frameRate := opts.FrameRate
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+
+	// check duration
+	if opts.Duration <= 0 {
+		return fmt.Errorf("missing duration")
+	}
+
+	// get load time
+	loadTime := opts.LoadTime
+	if loadTime <= 0 {
+		loadTime = 5 * time.Second
+	}
+
+	// get frames and step
+	frames := int(math.Ceil(opts.Duration.Seconds() * frameRate))
+	step := time.Duration(float64(time.Second) / frameRate)
+
+	// listen for expired budgets
+	expired := make(chan struct{}, 1)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if _, ok := ev.(*emulation.EventVirtualTimeBudgetExpired); ok {
+			select {
+			case expired <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	// prepare interception
+	_, err := intercept(ctx, ic, url, nil, nil, opts.Block, nil)
+	if err != nil {
+		return err
+	}
+
+	// prepare advance
+	advance := func(ctx context.Context, policy emulation.VirtualTimePolicy, budget time.Duration) error {
+		_, err := emulation.SetVirtualTimePolicy(policy).WithBudget(float64(budget) / float64(time.Millisecond)).Do(ctx)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-expired:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// capture frames
+	err = chromedp.Run(ctx,
+		viewport(ScreenshotOptions{Width: opts.Width, Height: opts.Height, Scale: opts.Scale}, opts.Height),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			// pause virtual time
+			_, err := emulation.SetVirtualTimePolicy(emulation.VirtualTimePolicyPause).Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			// navigate
+			_, _, errorText, err := page.Navigate(url).Do(ctx)
+			if err != nil {
+				return err
+			} else if errorText != "" {
+				return fmt.Errorf("navigation failed: %s", errorText)
+			}
+
+			// load page
+			err = advance(ctx, emulation.VirtualTimePolicyPauseIfNetworkFetchesPending, loadTime)
+			if err != nil {
+				return err
+			}
+
+			// capture frames
+			for i := 0; i < frames; i++ {
+				// advance time
+				if i > 0 {
+					err = advance(ctx, emulation.VirtualTimePolicyAdvance, step)
+					if err != nil {
+						return err
+					}
+				}
+
+				// capture frame
+				buf, err := page.CaptureScreenshot().
+					WithFormat(page.CaptureScreenshotFormatPng).
+					Do(ctx)
+				if err != nil {
+					return err
+				}
+
+				// write frame
+				_, err = w.Write(buf)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}),
+	)
+	if timedOut(ctx) {
+		return run.ErrTimeLimit
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
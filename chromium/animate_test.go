@@ -0,0 +1,111 @@
+package chromium
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const lottieAnimation = `{"v":"5.7.4","fr":25,"ip":0,"op":50,"w":320,"h":240,"layers":[]}`
+
+func TestLottieOptions(t *testing.T) {
+	opts, err := LottieOptions([]byte(lottieAnimation), AnimationOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, AnimationOptions{
+		Width:     320,
+		Height:    240,
+		FrameRate: 25,
+		Duration:  2 * time.Second,
+	}, opts)
+
+	opts, err = LottieOptions([]byte(lottieAnimation), AnimationOptions{
+		Width:     640,
+		Height:    480,
+		FrameRate: 10,
+		Duration:  time.Second,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, AnimationOptions{
+		Width:     640,
+		Height:    480,
+		FrameRate: 10,
+		Duration:  time.Second,
+	}, opts)
+
+	_, err = LottieOptions([]byte("foo"), AnimationOptions{})
+	assert.Error(t, err)
+}
+
+func TestLottieBundled(t *testing.T) {
+	buf, err := lottieFiles.ReadFile("lottie/lottie.min.js")
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf, []byte("/*! lottie-web 5.12.2 | MIT License")))
+	assert.Contains(t, string(buf), "loadAnimation")
+}
+
+func TestLottiePage(t *testing.T) {
+	html, err := lottiePage([]byte(`{"nm":"</script><script>alert(1)</script>"}`), "red; } body { color: blue")
+	assert.NoError(t, err)
+	assert.Contains(t, html, `animationData: {"nm":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"},`)
+	assert.NotContains(t, html, "alert(1)</script>")
+	assert.NotContains(t, html, "color: blue")
+	assert.Contains(t, html, `<script src="lottie.min.js"></script>`)
+}
+
+func TestAnimateHTML(t *testing.T) {
+	html := `<html><body style="margin: 0">
+		<div id="box" style="width: 100px; height: 100px; background: red"></div>
+		<script>
+			const start = performance.now();
+			function tick(now) {
+				document.getElementById('box').style.marginLeft = Math.round(now - start) / 10 + 'px';
+				requestAnimationFrame(tick);
+			}
+			requestAnimationFrame(tick);
+		</script>
+	</body></html>`
+
+	var buf bytes.Buffer
+	err := AnimateHTML(nil, html, nil, &buf, AnimationOptions{
+		Width:     200,
+		Height:    100,
+		FrameRate: 10,
+		Duration:  time.Second,
+	})
+	assert.NoError(t, err)
+
+	frames := bytes.Split(buf.Bytes(), []byte("\x89PNG\r\n\x1a\n"))
+	assert.Len(t, frames, 11)
+	for i := 2; i < len(frames); i++ {
+		assert.NotEqual(t, frames[i-1], frames[i])
+	}
+
+	err = AnimateHTML(nil, html, nil, &buf, AnimationOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, "missing duration", err.Error())
+}
+
+func TestAnimateHTMLOffline(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+	}))
+	defer server.Close()
+
+	html := `<html><body><img src="` + server.URL + `/image.png"></body></html>`
+
+	var buf bytes.Buffer
+	err := AnimateHTML(nil, html, nil, &buf, AnimationOptions{
+		Width:    100,
+		Height:   100,
+		Duration: 100 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, buf.Len())
+	assert.Zero(t, atomic.LoadInt64(&requests))
+}
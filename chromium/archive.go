@@ -0,0 +1,287 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// The limits of archived requests and the total size of archived response
+// bodies. Further requests are dropped and further bodies are truncated.
+const (
+	maxArchiveEntries = maxReportEntries
+	maxArchiveBytes   = 64 << 20
+)
+
+type archiveEntry struct {
+	request  *network.Request
+	response *network.Response
+	date     time.Time
+	redirect bool
+	finished bool
+}
+
+type archiver struct {
+	mutex   sync.Mutex
+	entries []*archiveEntry
+	ids     map[*archiveEntry]network.RequestID
+	current map[network.RequestID]*archiveEntry
+}
+
+func collectResponses(ctx context.Context) *archiver {
+	// prepare archiver
+	a := &archiver{
+		ids:     map[*archiveEntry]network.RequestID{},
+		current: map[network.RequestID]*archiveEntry{},
+	}
+
+	// collect responses
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		a.handle(ev)
+	})
+
+	return a
+}
+
+func (a *archiver) handle(ev interface{}) {
+	// acquire mutex
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// complete redirected request
+		if prev := a.current[ev.RequestID]; prev != nil && ev.RedirectResponse != nil {
+			prev.response = ev.RedirectResponse
+			prev.redirect = true
+		}
+
+		// drop request if limit is reached
+		if len(a.entries) >= maxArchiveEntries {
+			delete(a.current, ev.RequestID)
+			return
+		}
+
+		// add request
+		entry := &archiveEntry{
+			request: ev.Request,
+			date:    time.Now(),
+		}
+		if ev.WallTime != nil {
+			entry.date = ev.WallTime.Time()
+		}
+		a.entries = append(a.entries, entry)
+		a.ids[entry] = ev.RequestID
+		a.current[ev.RequestID] = entry
+	case *network.EventResponseReceived:
+		if entry := a.current[ev.RequestID]; entry != nil {
+			entry.response = ev.Response
+		}
+	case *network.EventLoadingFinished:
+		if entry := a.current[ev.RequestID]; entry != nil {
+			entry.finished = true
+		}
+	}
+}
+
+func snapshot(data *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// capture snapshot
+		str, err := page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// set data
+		*data = []byte(str)
+
+		return nil
+	})
+}
+
+func archive(a *archiver, data *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// get entries
+		a.mutex.Lock()
+		entries := append([]*archiveEntry{}, a.entries...)
+		a.mutex.Unlock()
+
+		// write info
+		var buf bytes.Buffer
+		writeRecord(&buf, "warcinfo", "", time.Now(), "application/warc-fields", []byte(
+			"software: mediakit\r\nformat: WARC File Format 1.1\r\n",
+		), nil)
+
+		// write entries
+		var size int
+		for _, entry := range entries {
+			// skip pending and non HTTP entries
+			a.mutex.Lock()
+			id, res, redirect, finished := a.ids[entry], entry.response, entry.redirect, entry.finished
+			a.mutex.Unlock()
+			if res == nil || !strings.HasPrefix(entry.request.URL, "http") {
+				continue
+			}
+
+			// get body, truncating bodies beyond the size limit
+			var body []byte
+			var truncated string
+			if !redirect {
+				var err error
+				if finished {
+					body, err = network.GetResponseBody(id).Do(ctx)
+				}
+				if !finished || err != nil {
+					truncated = "unspecified"
+				} else if size+len(body) > maxArchiveBytes {
+					body = nil
+					truncated = "length"
+				}
+				size += len(body)
+			}
+
+			// write request and response
+			reqID := writeRecord(&buf, "request", entry.request.URL, entry.date, "application/http;msgtype=request", requestBlock(entry.request, res), nil)
+			var extra [][2]string
+			extra = append(extra, [2]string{"WARC-Concurrent-To", reqID})
+			if res.RemoteIPAddress != "" {
+				extra = append(extra, [2]string{"WARC-IP-Address", res.RemoteIPAddress})
+			}
+			if truncated != "" {
+				extra = append(extra, [2]string{"WARC-Truncated", truncated})
+			}
+			writeRecord(&buf, "response", entry.request.URL, entry.date, "application/http;msgtype=response", responseBlock(res, body), extra)
+		}
+
+		// set data
+		*data = buf.Bytes()
+
+		return nil
+	})
+}
+
+func requestBlock(req *network.Request, res *network.Response) []byte {
+	// parse URL
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil
+	}
+
+	// get headers
+	headers := res.RequestHeaders
+	if len(headers) == 0 {
+		headers = req.Headers
+	}
+
+	// write request line
+	var buf bytes.Buffer
+	buf.WriteString(req.Method + " " + u.RequestURI() + " HTTP/1.1\r\n")
+
+	// write headers, omitting credentials
+	hasHost := false
+	for _, line := range headerLines(headers, []string{"cookie", "authorization", "proxy-authorization"}) {
+		if strings.HasPrefix(strings.ToLower(line), "host:") {
+			hasHost = true
+		}
+		buf.WriteString(line + "\r\n")
+	}
+	if !hasHost {
+		buf.WriteString("Host: " + u.Host + "\r\n")
+	}
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
+
+func responseBlock(res *network.Response, body []byte) []byte {
+	// get status text
+	statusText := res.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(int(res.Status))
+	}
+
+	// write status line
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 " + strconv.FormatInt(res.Status, 10) + " " + statusText + "\r\n")
+
+	// write headers, omitting cookies, the body is stored decoded
+	for _, line := range headerLines(res.Headers, []string{"set-cookie", "set-cookie2", "content-encoding", "transfer-encoding", "content-length"}) {
+		buf.WriteString(line + "\r\n")
+	}
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n")
+
+	// write body
+	buf.Write(body)
+
+	return buf.Bytes()
+}
+
+func headerLines(headers network.Headers, skip []string) []string {
+	// collect lines
+	var lines []string
+	for name, value := range headers {
+		if strings.HasPrefix(name, ":") || slices.Contains(skip, strings.ToLower(name)) {
+			continue
+		}
+		for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+			lines = append(lines, name+": "+v)
+		}
+	}
+
+	// sort lines
+	sort.Strings(lines)
+
+	return lines
+}
+
+func writeRecord(buf *bytes.Buffer, typ, uri string, date time.Time, contentType string, block []byte, extra [][2]string) string {
+	// prepare digest
+	sum := sha1.Sum(block)
+
+	// write header
+	id := "<urn:uuid:" + newUUID() + ">"
+	buf.WriteString("WARC/1.1\r\n")
+	buf.WriteString("WARC-Type: " + typ + "\r\n")
+	buf.WriteString("WARC-Record-ID: " + id + "\r\n")
+	buf.WriteString("WARC-Date: " + date.UTC().Format(time.RFC3339) + "\r\n")
+	if uri != "" {
+		buf.WriteString("WARC-Target-URI: " + uri + "\r\n")
+	}
+	for _, field := range extra {
+		buf.WriteString(field[0] + ": " + field[1] + "\r\n")
+	}
+	buf.WriteString("WARC-Block-Digest: sha1:" + base32.StdEncoding.EncodeToString(sum[:]) + "\r\n")
+	buf.WriteString("Content-Type: " + contentType + "\r\n")
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(block)) + "\r\n\r\n")
+
+	// write block
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	return id
+}
+
+func newUUID() string {
+	// generate random version 4 UUID
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,127 @@
+package chromium
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiver(t *testing.T) {
+	a := &archiver{
+		ids:     map[*archiveEntry]network.RequestID{},
+		current: map[network.RequestID]*archiveEntry{},
+	}
+
+	a.handle(&network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request:   &network.Request{URL: "http://example.org/", Method: "GET"},
+	})
+	a.handle(&network.EventRequestWillBeSent{
+		RequestID:        "1",
+		Request:          &network.Request{URL: "https://example.org/", Method: "GET"},
+		RedirectResponse: &network.Response{Status: 301},
+	})
+	a.handle(&network.EventResponseReceived{
+		RequestID: "1",
+		Response:  &network.Response{Status: 200},
+	})
+	a.handle(&network.EventLoadingFinished{RequestID: "1"})
+
+	assert.Len(t, a.entries, 2)
+	assert.Equal(t, int64(301), a.entries[0].response.Status)
+	assert.True(t, a.entries[0].redirect)
+	assert.False(t, a.entries[0].finished)
+	assert.Equal(t, int64(200), a.entries[1].response.Status)
+	assert.False(t, a.entries[1].redirect)
+	assert.True(t, a.entries[1].finished)
+
+	for i := range maxArchiveEntries {
+		a.handle(&network.EventRequestWillBeSent{
+			RequestID: network.RequestID(strconv.Itoa(i + 2)),
+			Request:   &network.Request{URL: "https://example.org/", Method: "GET"},
+		})
+	}
+	assert.Len(t, a.entries, maxArchiveEntries)
+	assert.Len(t, a.current, maxArchiveEntries-1)
+}
+
+func TestWARCBlocks(t *testing.T) {
+	req := &network.Request{
+		URL:    "https://example.org/path?q=1",
+		Method: "GET",
+		Headers: network.Headers{
+			"Accept":        "text/html",
+			"Cookie":        "session=1234",
+			"Authorization": "Basic dXNlcjpzZWNyZXQ=",
+		},
+	}
+	res := &network.Response{
+		Status: 200,
+		Headers: network.Headers{
+			"Content-Type":     "text/html",
+			"Content-Encoding": "gzip",
+			"Content-Length":   "10",
+			"Set-Cookie":       "a=1\nb=2",
+		},
+	}
+
+	assert.Equal(t, "GET /path?q=1 HTTP/1.1\r\n"+
+		"Accept: text/html\r\n"+
+		"Host: example.org\r\n"+
+		"\r\n", string(requestBlock(req, res)))
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Content-Type: text/html\r\n"+
+		"Content-Length: 5\r\n"+
+		"\r\n"+
+		"Hello", string(responseBlock(res, []byte("Hello"))))
+
+	var buf bytes.Buffer
+	id := writeRecord(&buf, "response", "https://example.org/", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "application/http;msgtype=response", []byte("block"), [][2]string{
+		{"WARC-Concurrent-To", "<urn:uuid:1>"},
+	})
+	assert.Regexp(t, `^<urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}>$`, id)
+	assert.Equal(t, "WARC/1.1\r\n"+
+		"WARC-Type: response\r\n"+
+		"WARC-Record-ID: "+id+"\r\n"+
+		"WARC-Date: 2024-01-02T03:04:05Z\r\n"+
+		"WARC-Target-URI: https://example.org/\r\n"+
+		"WARC-Concurrent-To: <urn:uuid:1>\r\n"+
+		"WARC-Block-Digest: sha1:AIKLJM2V2EOKR4WOIWUWRQTEMUN57P4D\r\n"+
+		"Content-Type: application/http;msgtype=response\r\n"+
+		"Content-Length: 5\r\n"+
+		"\r\n"+
+		"block\r\n\r\n", buf.String())
+}
+
+func TestCaptureArchive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/style.css" {
+			w.Header().Set("Content-Type", "text/css")
+			_, _ = w.Write([]byte("body { color: red; }"))
+			return
+		}
+		_, _ = w.Write([]byte(`<html><head><link rel="stylesheet" href="/style.css"></head><body>Hello</body></html>`))
+	}))
+	defer server.Close()
+
+	res, err := Capture(nil, server.URL, ScreenshotOptions{
+		Snapshot: true,
+		WARC:     true,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Image)
+	assert.Contains(t, string(res.Snapshot), "Hello")
+	assert.Contains(t, string(res.Snapshot), "body { color: red; }")
+	assert.True(t, strings.HasPrefix(string(res.WARC), "WARC/1.1\r\nWARC-Type: warcinfo\r\n"))
+	assert.Equal(t, 2, strings.Count(string(res.WARC), "WARC-Type: response\r\n"))
+	assert.Contains(t, string(res.WARC), "WARC-Target-URI: "+server.URL+"/style.css\r\n")
+	assert.Contains(t, string(res.WARC), "body { color: red; }")
+}
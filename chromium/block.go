@@ -0,0 +1,118 @@
+package chromium
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// Trackers are URL patterns of common ad and tracking services.
+var Trackers = []string{
+	"*://*.doubleclick.net/*",
+	"*://*.googlesyndication.com/*",
+	"*://*.googleadservices.com/*",
+	"*://*.google-analytics.com/*",
+	"*://*.googletagmanager.com/*",
+	"*://*.googletagservices.com/*",
+	"*://*.adnxs.com/*",
+	"*://*.criteo.com/*",
+	"*://*.facebook.net/*",
+	"*://connect.facebook.com/*",
+	"*://*.hotjar.com/*",
+	"*://*.segment.io/*",
+	"*://*.mixpanel.com/*",
+	"*://*.scorecardresearch.com/*",
+}
+
+// Blocking defines the requests that are blocked while capturing. Blocked
+// requests fail as if blocked by the client.
+type Blocking struct {
+	// The URL patterns to block, "*" matches any sequence of characters
+	// (e.g. "*://*.example.com/*" or "*.mp4").
+	Patterns []string
+
+	// The resource types to block (e.g. network.ResourceTypeMedia).
+	Types []network.ResourceType
+
+	// Whether to block requests to hosts other than the captured page's host.
+	ThirdParty bool
+
+	// Whether to block all requests except to the allowed origins (e.g.
+	// "https://cdn.example.com"). The captured page's origin and the origin
+	// of rendered HTML are always allowed.
+	Offline bool
+	Allow   []string
+}
+
+type blocker struct {
+	patterns []*regexp.Regexp
+	types    []network.ResourceType
+	host     string
+	origins  []string
+	third    bool
+	offline  bool
+}
+
+func newBlocker(b *Blocking, pageURL string) *blocker {
+	// check blocking
+	if b == nil {
+		return nil
+	}
+
+	// compile patterns
+	var patterns []*regexp.Regexp
+	for _, pattern := range b.Patterns {
+		expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`)
+		patterns = append(patterns, regexp.MustCompile(`^`+expr+`$`))
+	}
+
+	// get host
+	var host string
+	if u, err := url.Parse(pageURL); err == nil {
+		host = u.Hostname()
+	}
+
+	return &blocker{
+		patterns: patterns,
+		types:    b.Types,
+		host:     host,
+		origins:  append([]string{originOf(pageURL)}, b.Allow...),
+		third:    b.ThirdParty,
+		offline:  b.Offline,
+	}
+}
+
+func (b *blocker) blocked(u *url.URL, typ network.ResourceType) bool {
+	// check blocker
+	if b == nil {
+		return false
+	}
+
+	// check origin
+	if b.offline && !slices.Contains(b.origins, u.Scheme+"://"+u.Host) {
+		return true
+	}
+
+	// check host
+	if b.third && u.Hostname() != b.host {
+		return true
+	}
+
+	// check type
+	if slices.Contains(b.types, typ) {
+		return true
+	}
+
+	// check patterns
+	str := u.String()
+	for _, pattern := range b.patterns {
+		if pattern.MatchString(str) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,86 @@
+package chromium
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocker(t *testing.T) {
+	check := func(b *blocker, rawURL string, typ network.ResourceType) bool {
+		u, err := url.Parse(rawURL)
+		assert.NoError(t, err)
+		return b.blocked(u, typ)
+	}
+
+	var b *blocker
+	assert.False(t, check(b, "https://example.org/", network.ResourceTypeDocument))
+
+	b = newBlocker(&Blocking{
+		Patterns: append([]string{"*.mp4"}, Trackers...),
+		Types:    []network.ResourceType{network.ResourceTypeMedia},
+	}, "https://example.org/")
+	assert.False(t, check(b, "https://example.org/", network.ResourceTypeDocument))
+	assert.False(t, check(b, "https://cdn.example.com/app.js", network.ResourceTypeScript))
+	assert.True(t, check(b, "https://example.org/video.mp4", network.ResourceTypeOther))
+	assert.True(t, check(b, "https://example.org/audio", network.ResourceTypeMedia))
+	assert.True(t, check(b, "https://www.google-analytics.com/analytics.js", network.ResourceTypeScript))
+
+	b = newBlocker(&Blocking{
+		ThirdParty: true,
+	}, "https://example.org/")
+	assert.False(t, check(b, "https://example.org/app.js", network.ResourceTypeScript))
+	assert.False(t, check(b, "http://example.org:8080/app.js", network.ResourceTypeScript))
+	assert.True(t, check(b, "https://cdn.example.com/app.js", network.ResourceTypeScript))
+
+	b = newBlocker(&Blocking{
+		Offline: true,
+		Allow:   []string{"https://cdn.example.com"},
+	}, "https://example.org/")
+	assert.False(t, check(b, "https://example.org/app.js", network.ResourceTypeScript))
+	assert.False(t, check(b, "https://cdn.example.com/app.js", network.ResourceTypeScript))
+	assert.True(t, check(b, "http://example.org/app.js", network.ResourceTypeScript))
+	assert.True(t, check(b, "https://other.example.com/app.js", network.ResourceTypeScript))
+}
+
+func TestRenderHTMLOffline(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+	}))
+	defer server.Close()
+
+	html := `<html><body><img src="` + server.URL + `/image.png"></body></html>`
+
+	/* default */
+
+	buf, err := RenderHTML(nil, html, nil, RenderOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+	assert.Zero(t, atomic.LoadInt64(&requests))
+
+	/* pdf */
+
+	buf, err = RenderHTML(nil, html, nil, RenderOptions{
+		PDF: &PDFOptions{},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+	assert.Zero(t, atomic.LoadInt64(&requests))
+
+	/* allowed */
+
+	buf, err = RenderHTML(nil, html, nil, RenderOptions{
+		Screenshot: ScreenshotOptions{
+			Block: &Blocking{},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&requests))
+}
@@ -0,0 +1,230 @@
+package chromium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Clip defines a page region in CSS pixels.
+type Clip struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// Geolocation defines an emulated position.
+type Geolocation struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  float64
+}
+
+const elementClip = `
+(() => {
+	const el = document.querySelector(%s);
+	if (!el) {
+		return null;
+	}
+	const rect = el.getBoundingClientRect();
+	return {
+		x: rect.left + window.scrollX,
+		y: rect.top + window.scrollY,
+		width: rect.width,
+		height: rect.height,
+	};
+})()
+`
+
+const injectStyle = `
+(() => {
+	const style = document.createElement('style');
+	style.textContent = %s;
+	document.head.appendChild(style);
+})()
+`
+
+func emulate(opts ScreenshotOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// emulate viewport
+		err := viewport(opts, opts.Height).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// emulate media
+		var features []*emulation.MediaFeature
+		if opts.ColorScheme != "" {
+			features = append(features, &emulation.MediaFeature{Name: "prefers-color-scheme", Value: opts.ColorScheme})
+		}
+		if opts.ReducedMotion {
+			features = append(features, &emulation.MediaFeature{Name: "prefers-reduced-motion", Value: "reduce"})
+		}
+		if opts.Media != "" || features != nil {
+			err = emulation.SetEmulatedMedia().WithMedia(opts.Media).WithFeatures(features).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// emulate timezone
+		if opts.Timezone != "" {
+			err = emulation.SetTimezoneOverride(opts.Timezone).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// emulate locale
+		if opts.Locale != "" {
+			err = emulation.SetLocaleOverride().WithLocale(opts.Locale).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// override user agent and language
+		if opts.UserAgent != "" || opts.Locale != "" {
+			err = userAgent(opts).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// emulate geolocation
+		if opts.Geolocation != nil {
+			grant := browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation})
+			if id := chromedp.FromContext(ctx).BrowserContextID; id != "" {
+				grant = grant.WithBrowserContextID(id)
+			}
+			err = grant.Do(browserExecutor(ctx))
+			if err != nil {
+				return err
+			}
+			err = emulation.SetGeolocationOverride().
+				WithLatitude(opts.Geolocation.Latitude).
+				WithLongitude(opts.Geolocation.Longitude).
+				WithAccuracy(opts.Geolocation.Accuracy).
+				Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func viewport(opts ScreenshotOptions, height int64) chromedp.Action {
+	// prepare options
+	viewportOpts := []chromedp.EmulateViewportOption{chromedp.EmulateScale(opts.Scale)}
+	if opts.Mobile {
+		viewportOpts = append(viewportOpts, chromedp.EmulateMobile, chromedp.EmulateTouch)
+	}
+
+	return chromedp.EmulateViewport(opts.Width, height, viewportOpts...)
+}
+
+func userAgent(opts ScreenshotOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// get default user agent
+		userAgent := opts.UserAgent
+		if userAgent == "" {
+			var err error
+			_, _, _, userAgent, _, err = browser.GetVersion().Do(browserExecutor(ctx))
+			if err != nil {
+				return err
+			}
+		}
+
+		// override user agent and language
+		return emulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(opts.Locale).Do(ctx)
+	})
+}
+
+func inject(opts ScreenshotOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// prepare style
+		var style []string
+		for _, sel := range opts.Hide {
+			style = append(style, sel+" { visibility: hidden !important; }")
+		}
+		for _, sel := range opts.Remove {
+			style = append(style, sel+" { display: none !important; }")
+		}
+		if opts.CSS != "" {
+			style = append(style, opts.CSS)
+		}
+
+		// inject style
+		if len(style) > 0 {
+			err := chromedp.Evaluate(fmt.Sprintf(injectStyle, jsString(strings.Join(style, "\n"))), nil).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// run script
+		if opts.Script != "" {
+			err := chromedp.Evaluate(opts.Script, nil, func(params *runtime.EvaluateParams) *runtime.EvaluateParams {
+				return params.WithAwaitPromise(true)
+			}).Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func clip(ctx context.Context, opts ScreenshotOptions) (*page.Viewport, error) {
+	// handle explicit clip
+	if opts.Clip != nil {
+		return &page.Viewport{
+			X:      opts.Clip.X,
+			Y:      opts.Clip.Y,
+			Width:  opts.Clip.Width,
+			Height: opts.Clip.Height,
+			Scale:  1,
+		}, nil
+	}
+
+	// handle selector
+	if opts.Selector != "" {
+		var rect *Clip
+		err := chromedp.Evaluate(fmt.Sprintf(elementClip, jsString(opts.Selector)), &rect).Do(ctx)
+		if err != nil {
+			return nil, err
+		} else if rect == nil {
+			return nil, fmt.Errorf("element not found: %s", opts.Selector)
+		}
+		return &page.Viewport{
+			X:      rect.X,
+			Y:      rect.Y,
+			Width:  rect.Width,
+			Height: rect.Height,
+			Scale:  1,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func jsString(str string) string {
+	buf, _ := json.Marshal(str)
+	return string(buf)
+}
+
+func browserExecutor(ctx context.Context) context.Context {
+	return cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Browser)
+}
@@ -0,0 +1,156 @@
+package chromium
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/256dpi/xo"
+)
+
+// ErrBlocked is returned when a navigation is blocked by a guard.
+var ErrBlocked = xo.BF("blocked by guard")
+
+// the special-purpose address ranges that are not globally reachable or embed
+// other addresses (IANA IPv4 and IPv6 special-purpose address registries)
+var specialNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/127"),
+	netip.MustParsePrefix("::ffff:0:0/96"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("3fff::/20"),
+	netip.MustParsePrefix("5f00::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fec0::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// Guard restricts the URLs a capture may navigate to and request. Only
+// "http" and "https" URLs are allowed whose hosts do not resolve to loopback,
+// link-local, private or otherwise special-purpose addresses. The check is
+// applied to the captured URL, redirects and subrequests. WebSocket
+// connections and service workers are disabled as their traffic cannot be
+// checked.
+//
+// Note: Hosts are resolved separately from the browser. A guard is therefore
+// not a replacement for network level egress filtering.
+type Guard struct {
+	// The additionally allowed schemes (e.g. "data").
+	Schemes []string
+
+	// The allowed host names and addresses, which are not checked.
+	Hosts []string
+
+	// The allowed address ranges (e.g. "10.1.0.0/16").
+	Networks []netip.Prefix
+
+	// The resolver used to look up host names, defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// Check will check the provided URL and return an ErrBlocked error if it is
+// not allowed.
+func (g *Guard) Check(ctx context.Context, rawURL string) error {
+	// check guard
+	if g == nil {
+		return nil
+	}
+
+	// parse URL
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrBlocked.WrapF("invalid URL %q", rawURL)
+	}
+
+	// check scheme
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		if slices.Contains(g.Schemes, scheme) {
+			return nil
+		}
+		return ErrBlocked.WrapF("scheme %q not allowed", scheme)
+	}
+
+	// check host
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return ErrBlocked.WrapF("missing host in %q", rawURL)
+	} else if slices.Contains(g.Hosts, host) {
+		return nil
+	}
+
+	// get addresses
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		resolver := g.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err = resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil || len(addrs) == 0 {
+			return ErrBlocked.WrapF("host %q not resolvable", host)
+		}
+	}
+
+	// check addresses
+	for _, addr := range addrs {
+		if !g.Allowed(addr) {
+			return ErrBlocked.WrapF("address %s of host %q not allowed", addr, host)
+		}
+	}
+
+	return nil
+}
+
+// Allowed returns whether the provided address is allowed. It may be used to
+// guard connections made outside the browser.
+func (g *Guard) Allowed(addr netip.Addr) bool {
+	// unmap address
+	addr = addr.Unmap()
+
+	// check networks
+	for _, network := range g.Networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+
+	// check address
+	if !addr.IsGlobalUnicast() {
+		return false
+	}
+
+	// check special-purpose networks
+	for _, network := range specialNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,129 @@
+package chromium
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardCheck(t *testing.T) {
+	ctx := context.Background()
+
+	var guard *Guard
+	assert.NoError(t, guard.Check(ctx, "http://127.0.0.1"))
+
+	guard = &Guard{}
+	for _, u := range []string{
+		"file:///etc/passwd",
+		"data:text/html,hello",
+		"ftp://example.org",
+		"http://",
+		"http://127.0.0.1",
+		"http://localhost:8080",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1",
+		"http://172.16.0.1",
+		"http://192.168.1.1",
+		"http://100.64.0.1",
+		"http://0.0.0.0",
+		"http://[::1]",
+		"http://[fe80::1]",
+		"http://[fd00::1]",
+		"http://[::ffff:127.0.0.1]",
+		"http://192.0.0.1",
+		"http://192.0.2.1",
+		"http://198.18.0.1",
+		"http://198.51.100.1",
+		"http://203.0.113.1",
+		"http://240.0.0.1",
+		"http://[64:ff9b::7f00:1]",
+		"http://[2001::1]",
+		"http://[2001:db8::1]",
+		"http://[2002:7f00:1::1]",
+	} {
+		err := guard.Check(ctx, u)
+		assert.Error(t, err, u)
+		assert.True(t, ErrBlocked.Is(err), u)
+	}
+
+	assert.NoError(t, guard.Check(ctx, "https://1.1.1.1/"))
+	assert.NoError(t, guard.Check(ctx, "http://[2606:4700:4700::1111]/"))
+
+	guard = &Guard{
+		Schemes:  []string{"data"},
+		Hosts:    []string{"localhost"},
+		Networks: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")},
+	}
+	assert.NoError(t, guard.Check(ctx, "data:text/html,hello"))
+	assert.NoError(t, guard.Check(ctx, "http://localhost:8080"))
+	assert.NoError(t, guard.Check(ctx, "http://10.1.2.3"))
+	assert.Error(t, guard.Check(ctx, "http://10.2.0.1"))
+}
+
+func TestScreenshotGuard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, strings.Replace(r.Host, "127.0.0.1", "http://localhost", 1)+"/", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>Hello</body></html>"))
+	}))
+	defer server.Close()
+
+	buf, err := Screenshot(nil, server.URL, ScreenshotOptions{
+		Guard: &Guard{},
+	})
+	assert.Error(t, err)
+	assert.True(t, ErrBlocked.Is(err))
+	assert.Nil(t, buf)
+
+	buf, err = Screenshot(nil, server.URL, ScreenshotOptions{
+		Guard: &Guard{Hosts: []string{"127.0.0.1"}},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+
+	buf, err = Screenshot(nil, server.URL+"/redirect", ScreenshotOptions{
+		Guard: &Guard{Hosts: []string{"127.0.0.1"}},
+	})
+	assert.Error(t, err)
+	assert.True(t, ErrBlocked.Is(err))
+	assert.Nil(t, buf)
+}
+
+func TestScreenshotGuardUnchecked(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ws" || r.URL.Path == "/sw.js" {
+			mutex.Lock()
+			paths = append(paths, r.URL.Path)
+			mutex.Unlock()
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<html><body><script>
+			const ws = new WebSocket("ws://" + location.host + "/ws");
+			const sw = navigator.serviceWorker.register("/sw.js");
+			ws.onerror = () => sw.catch(() => window.done = true);
+		</script></body></html>`))
+	}))
+	defer server.Close()
+
+	buf, err := Screenshot(nil, server.URL, ScreenshotOptions{
+		Guard:   &Guard{Hosts: []string{"127.0.0.1"}},
+		WaitFor: []Condition{Expression("window.done === true")},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+
+	mutex.Lock()
+	assert.Empty(t, paths)
+	mutex.Unlock()
+}
@@ -0,0 +1,101 @@
+package chromium
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Metadata is the metadata of a page.
+type Metadata struct {
+	// The final URL after redirects.
+	URL string `json:"url"`
+
+	// The title, description, canonical URL and language.
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Canonical   string `json:"canonical"`
+	Language    string `json:"language"`
+
+	// The favicon and touch icon URLs. If no favicon is declared, the
+	// conventional "/favicon.ico" URL is returned.
+	Favicons   []string `json:"favicons"`
+	TouchIcons []string `json:"touchIcons"`
+
+	// The Open Graph ("og:") and Twitter card ("twitter:") tags without their
+	// prefix.
+	OpenGraph map[string]string `json:"openGraph"`
+	Twitter   map[string]string `json:"twitter"`
+}
+
+const inspectPage = `
+(() => {
+	const meta = (name) => {
+		const el = document.querySelector('meta[name="' + name + '" i], meta[property="' + name + '" i]');
+		return el ? el.content || '' : '';
+	};
+	const tags = (prefix) => {
+		const tags = {};
+		for (const el of document.querySelectorAll('meta[property], meta[name]')) {
+			const key = (el.getAttribute('property') || el.getAttribute('name')).toLowerCase();
+			if (key.startsWith(prefix) && !(key.slice(prefix.length) in tags)) {
+				tags[key.slice(prefix.length)] = el.content || '';
+			}
+		}
+		return tags;
+	};
+	const links = (...rels) => Array.from(document.querySelectorAll('link[rel][href]'))
+		.filter((el) => el.rel.toLowerCase().split(/\s+/).some((rel) => rels.includes(rel)))
+		.map((el) => el.href);
+	const canonical = document.querySelector('link[rel="canonical" i][href]');
+	const favicons = links('icon');
+	return {
+		url: location.href,
+		title: document.title,
+		description: meta('description'),
+		canonical: canonical ? canonical.href : '',
+		language: document.documentElement.lang || meta('content-language') ||
+			(document.querySelector('meta[http-equiv="content-language" i]') || {}).content || '',
+		favicons: favicons.length ? favicons : (location.protocol.startsWith('http') ? [location.origin + '/favicon.ico'] : []),
+		touchIcons: links('apple-touch-icon', 'apple-touch-icon-precomposed'),
+		openGraph: tags('og:'),
+		twitter: tags('twitter:'),
+	};
+})()
+`
+
+// Inspect will load the given URL and return the page metadata. The options
+// are used to load the page, no screenshot is captured. A browser context may
+// be provided using Allocate, otherwise a new one will be allocated.
+func Inspect(ctx context.Context, url string, opts ScreenshotOptions) (*Metadata, error) {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// inspect page
+	res, err := capture(ctx, url, opts, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Metadata, nil
+}
+
+func inspect(metadata **Metadata) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// evaluate metadata
+		var m Metadata
+		err := chromedp.Evaluate(inspectPage, &m).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// set metadata
+		*metadata = &m
+
+		return nil
+	})
+}
@@ -0,0 +1,66 @@
+package chromium
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const metadataPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<title>Example</title>
+	<meta name="description" content="An example page.">
+	<meta property="og:title" content="Example OG">
+	<meta property="og:image" content="/image.png">
+	<meta name="twitter:card" content="summary">
+	<link rel="canonical" href="/page">
+	<link rel="shortcut icon" href="/icon.png">
+	<link rel="apple-touch-icon" href="/touch.png">
+</head>
+<body>Hello</body>
+</html>`
+
+func TestInspect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte(metadataPage))
+	}))
+	defer server.Close()
+
+	metadata, err := Inspect(nil, server.URL, ScreenshotOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, &Metadata{
+		URL:         server.URL + "/page",
+		Title:       "Example",
+		Description: "An example page.",
+		Canonical:   server.URL + "/page",
+		Language:    "en",
+		Favicons:    []string{server.URL + "/icon.png"},
+		TouchIcons:  []string{server.URL + "/touch.png"},
+		OpenGraph: map[string]string{
+			"title": "Example OG",
+			"image": "/image.png",
+		},
+		Twitter: map[string]string{
+			"card": "summary",
+		},
+	}, metadata)
+
+	res, err := Capture(nil, server.URL, ScreenshotOptions{
+		Inspect: true,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Image)
+	assert.Equal(t, metadata, res.Metadata)
+
+	res, err = Capture(nil, server.URL, ScreenshotOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Image)
+	assert.Nil(t, res.Metadata)
+}
@@ -0,0 +1,282 @@
+package chromium
+
+import (
+	"context"
+	"encoding/base64"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+const disableServiceWorkers = `
+if (typeof ServiceWorkerContainer !== "undefined") {
+	ServiceWorkerContainer.prototype.register = function() {
+		return Promise.reject(new DOMException("service workers are disabled", "SecurityError"));
+	};
+}
+`
+
+// interceptor handles requests paused by the fetch domain. A single
+// interceptor is used per tab.
+type interceptor struct {
+	// the origin served from memory
+	origin string
+	html   []byte
+	assets fs.FS
+
+	// the origin of the captured page
+	pageOrigin string
+
+	// the credentials and headers provided to the page origin
+	auth    *Credentials
+	headers map[string]string
+
+	// the blocked requests
+	block *blocker
+
+	// the guard applied to requests and the main frame
+	guard   *Guard
+	blocked error
+
+	mutex    sync.Mutex
+	attempts map[fetch.RequestID]bool
+}
+
+func intercept(ctx context.Context, ic *interceptor, url string, auth *Credentials, headers map[string]string, block *Blocking, guard *Guard) (*interceptor, error) {
+	// prepare interceptor
+	if ic == nil {
+		ic = &interceptor{}
+	}
+	ic.pageOrigin = originOf(url)
+	ic.auth = auth
+	ic.headers = headers
+	ic.block = newBlocker(block, url)
+	ic.guard = guard
+
+	// check URL
+	err := ic.check(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// enable interception
+	if ic.active() {
+		err = ic.enable(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ic, nil
+}
+
+func (i *interceptor) active() bool {
+	return i != nil && (i.origin != "" || i.auth != nil || len(i.headers) > 0 || i.block != nil || i.guard != nil)
+}
+
+func (i *interceptor) enable(ctx context.Context) error {
+	// handle paused requests and auth challenges
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go func() {
+				_ = chromedp.Run(ctx, i.handle(ctx, ev))
+			}()
+		case *fetch.EventAuthRequired:
+			go func() {
+				_ = chromedp.Run(ctx, i.authenticate(ev))
+			}()
+		}
+	})
+
+	// block WebSockets and service workers when guarded as their traffic
+	// is not paused by the fetch domain
+	if i.guard != nil {
+		err := chromedp.Run(ctx,
+			network.SetBlockedURLs([]string{"ws://*", "wss://*"}),
+			network.SetBypassServiceWorker(true),
+			chromedp.ActionFunc(func(ctx context.Context) error {
+				_, err := page.AddScriptToEvaluateOnNewDocument(disableServiceWorkers).Do(ctx)
+				return err
+			}),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// enable fetch domain
+	return chromedp.Run(ctx, fetch.Enable().WithHandleAuthRequests(i.auth != nil))
+}
+
+func (i *interceptor) handle(ctx context.Context, ev *fetch.EventRequestPaused) chromedp.Action {
+	// parse URL
+	u, err := url.Parse(ev.Request.URL)
+	if err != nil {
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	}
+
+	// serve origin
+	if i.origin != "" && u.Scheme+"://"+u.Host == i.origin {
+		return i.serve(ev.RequestID, u.Path)
+	}
+
+	// guard request
+	err = i.guard.Check(ctx, ev.Request.URL)
+	if err != nil {
+		if ev.ResourceType == network.ResourceTypeDocument && ev.FrameID == mainFrame(ctx) {
+			i.mutex.Lock()
+			if i.blocked == nil {
+				i.blocked = err
+			}
+			i.mutex.Unlock()
+		}
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	}
+
+	// block request
+	if i.block.blocked(u, ev.ResourceType) {
+		return fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	}
+
+	// add headers to requests of the page origin
+	if len(i.headers) > 0 && u.Scheme+"://"+u.Host == i.pageOrigin {
+		return fetch.ContinueRequest(ev.RequestID).WithHeaders(mergeHeaders(ev.Request.Headers, i.headers))
+	}
+
+	return fetch.ContinueRequest(ev.RequestID)
+}
+
+func (i *interceptor) check(ctx context.Context, rawURL string) error {
+	// check served origin
+	if i.origin != "" && originOf(rawURL) == i.origin {
+		return nil
+	}
+
+	return i.guard.Check(ctx, rawURL)
+}
+
+func (i *interceptor) err() error {
+	// acquire mutex
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	return i.blocked
+}
+
+func (i *interceptor) authenticate(ev *fetch.EventAuthRequired) chromedp.Action {
+	// acquire mutex
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	// provide credentials once per request to the page origin only
+	response := &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseCancelAuth,
+	}
+	if i.auth != nil && ev.AuthChallenge.Origin == i.pageOrigin && !i.attempts[ev.RequestID] {
+		response = &fetch.AuthChallengeResponse{
+			Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+			Username: i.auth.Username,
+			Password: i.auth.Password,
+		}
+		if i.attempts == nil {
+			i.attempts = map[fetch.RequestID]bool{}
+		}
+		i.attempts[ev.RequestID] = true
+	}
+
+	return fetch.ContinueWithAuth(ev.RequestID, response)
+}
+
+func (i *interceptor) serve(id fetch.RequestID, urlPath string) chromedp.Action {
+	// serve document
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" {
+		return fulfill(id, http.StatusOK, "text/html; charset=utf-8", i.html)
+	}
+
+	// check assets
+	if i.assets == nil || !fs.ValidPath(name) {
+		return fulfill(id, http.StatusNotFound, "text/plain", nil)
+	}
+
+	// read asset
+	buf, err := fs.ReadFile(i.assets, name)
+	if err != nil {
+		return fulfill(id, http.StatusNotFound, "text/plain", nil)
+	}
+
+	// get content type
+	typ := mime.TypeByExtension(path.Ext(name))
+	if typ == "" {
+		typ = http.DetectContentType(buf)
+	}
+
+	return fulfill(id, http.StatusOK, typ, buf)
+}
+
+func mainFrame(ctx context.Context) cdp.FrameID {
+	// the main frame shares the identifier of its target
+	if c := chromedp.FromContext(ctx); c != nil && c.Target != nil {
+		return cdp.FrameID(c.Target.TargetID)
+	}
+
+	return ""
+}
+
+func fulfill(id fetch.RequestID, status int64, typ string, body []byte) chromedp.Action {
+	return fetch.FulfillRequest(id, status).
+		WithResponseHeaders([]*fetch.HeaderEntry{
+			{Name: "Content-Type", Value: typ},
+		}).
+		WithBody(base64.StdEncoding.EncodeToString(body))
+}
+
+func mergeHeaders(headers network.Headers, extra map[string]string) []*fetch.HeaderEntry {
+	// keep headers that are not overridden
+	var list []*fetch.HeaderEntry
+	for name, value := range headers {
+		overridden := false
+		for key := range extra {
+			if strings.EqualFold(name, key) {
+				overridden = true
+			}
+		}
+		if str, ok := value.(string); ok && !overridden {
+			list = append(list, &fetch.HeaderEntry{Name: name, Value: str})
+		}
+	}
+
+	// add extra headers
+	for name, value := range extra {
+		list = append(list, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+
+	// sort headers
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+func originOf(rawURL string) string {
+	// parse URL
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
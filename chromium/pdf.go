@@ -0,0 +1,190 @@
+package chromium
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+// Paper defines a paper size in inches.
+type Paper struct {
+	Width  float64
+	Height float64
+}
+
+// The common paper sizes.
+var (
+	Letter  = Paper{Width: 8.5, Height: 11}
+	Legal   = Paper{Width: 8.5, Height: 14}
+	Tabloid = Paper{Width: 11, Height: 17}
+	A3      = Paper{Width: 11.69, Height: 16.54}
+	A4      = Paper{Width: 8.27, Height: 11.69}
+	A5      = Paper{Width: 5.83, Height: 8.27}
+)
+
+// Margins defines page margins in inches.
+type Margins struct {
+	Top    float64
+	Right  float64
+	Bottom float64
+	Left   float64
+}
+
+// PDFOptions are the options used for printing a PDF.
+type PDFOptions struct {
+	// The paper size, defaults to Letter.
+	Paper Paper
+
+	// The page margins, defaults to no margins.
+	Margins Margins
+
+	// Whether to use landscape orientation.
+	Landscape bool
+
+	// The HTML templates for the header and footer. Elements with the classes
+	// date, title, url, pageNumber and totalPages are filled with the printing
+	// values. The header and footer are only displayed if a template is set.
+	HeaderTemplate string
+	FooterTemplate string
+
+	// Whether to print background graphics.
+	Background bool
+
+	// The page ranges to print, e.g. "1-5, 8, 11-13". Defaults to all pages.
+	PageRanges string
+
+	// The rendering scale, defaults to 1.
+	Scale float64
+
+	// Whether to prefer the page size defined by CSS.
+	PreferCSSPageSize bool
+
+	// Whether to fail on log errors.
+	Pedantic bool
+
+	// The time to wait before printing.
+	Wait time.Duration
+
+	// The conditions awaited in order before printing and their overall
+	// deadline, defaults to DefaultWaitTimeout.
+	WaitFor     []Condition
+	WaitTimeout time.Duration
+
+	// The extra HTTP headers sent with requests to the printed URL's origin
+	// and the cookies set before navigating.
+	Headers map[string]string
+	Cookies []Cookie
+
+	// The credentials provided to basic auth challenges of the printed URL's
+	// origin.
+	BasicAuth *Credentials
+
+	// The requests to block.
+	Block *Blocking
+
+	// The guard applied to the URL, redirects and subrequests.
+	Guard *Guard
+}
+
+// PrintPDF will print the given URL as a PDF. A browser context may be
+// provided using Allocate, otherwise a new one will be allocated.
+func PrintPDF(ctx context.Context, url string, opts PDFOptions) ([]byte, error) {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return printPDF(ctx, url, opts, nil)
+}
+
+func printPDF(ctx context.Context, url string, opts PDFOptions, ic *interceptor) ([]byte, error) {
+	// collect errors and track requests
+	logErrors := collectErrors(ctx)
+	requests := track(ctx)
+
+	// prepare interception
+	ic, err := intercept(ctx, ic, url, opts.BasicAuth, opts.Headers, opts.Block, opts.Guard)
+	if err != nil {
+		return nil, err
+	}
+
+	// get paper
+	paper := opts.Paper
+	if paper == (Paper{}) {
+		paper = Letter
+	}
+
+	// prepare params
+	params := page.PrintToPDF().
+		WithPaperWidth(paper.Width).
+		WithPaperHeight(paper.Height).
+		WithMarginTop(opts.Margins.Top).
+		WithMarginRight(opts.Margins.Right).
+		WithMarginBottom(opts.Margins.Bottom).
+		WithMarginLeft(opts.Margins.Left).
+		WithLandscape(opts.Landscape).
+		WithPrintBackground(opts.Background).
+		WithPageRanges(opts.PageRanges).
+		WithPreferCSSPageSize(opts.PreferCSSPageSize)
+	if opts.Scale > 0 {
+		params = params.WithScale(opts.Scale)
+	}
+	if opts.HeaderTemplate != "" || opts.FooterTemplate != "" {
+		// an empty template would show the default header or footer
+		params = params.
+			WithDisplayHeaderFooter(true).
+			WithHeaderTemplate(lo.CoalesceOrEmpty(opts.HeaderTemplate, "<span></span>")).
+			WithFooterTemplate(lo.CoalesceOrEmpty(opts.FooterTemplate, "<span></span>"))
+	}
+
+	// print PDF
+	var buf []byte
+	err = chromedp.Run(ctx,
+		withTimeout(10*time.Second, "session setup failed", session(url, opts.Cookies)),
+		withTimeout(20*time.Second, "navigation failed", chromedp.Navigate(url)),
+		withTimeout(20*time.Second, "awaiting body failed", chromedp.WaitReady("body")),
+		wait(opts.WaitFor, opts.WaitTimeout, requests),
+		withTimeout(opts.Wait+20*time.Second, "printing failed", chromedp.ActionFunc(func(ctx context.Context) error {
+			// wait some time
+			if opts.Wait > 0 {
+				time.Sleep(opts.Wait)
+			}
+
+			// print page
+			var err error
+			buf, _, err = params.Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		})),
+	)
+	if blocked := ic.err(); blocked != nil {
+		return nil, blocked
+	} else if timedOut(ctx) {
+		return nil, run.ErrTimeLimit
+	} else if err != nil {
+		return nil, err
+	}
+
+	// check output
+	if Limits.Output > 0 && int64(len(buf)) > Limits.Output {
+		return nil, run.ErrOutputLimit
+	}
+
+	// handle log errors
+	if opts.Pedantic && len(logErrors()) > 0 {
+		return nil, fmt.Errorf("log errors: %s", logErrors())
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,26 @@
+package chromium
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintPDF(t *testing.T) {
+	buf, err := PrintPDF(nil, "https://example.org", PDFOptions{})
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf, []byte("%PDF-")))
+
+	buf, err = PrintPDF(nil, "https://example.org", PDFOptions{
+		Paper:          A4,
+		Margins:        Margins{Top: 1, Right: 0.5, Bottom: 1, Left: 0.5},
+		Landscape:      true,
+		HeaderTemplate: `<span class="title"></span>`,
+		FooterTemplate: `<span class="pageNumber"></span>/<span class="totalPages"></span>`,
+		Background:     true,
+		PageRanges:     "1",
+	})
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf, []byte("%PDF-")))
+}
@@ -0,0 +1,306 @@
+package chromium
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+// ErrPoolClosed is returned when acquiring a context from a closed pool.
+var ErrPoolClosed = xo.BF("pool closed")
+
+// PoolConfig defines the configuration of a pool.
+type PoolConfig struct {
+	// The number of browsers kept running, defaults to one.
+	Size int
+
+	// The number of contexts handed out by a browser before it is replaced.
+	// Zero means browsers are only replaced after a crash.
+	MaxUses int
+
+	// The timeout applied to each acquired context.
+	Timeout time.Duration
+}
+
+// Pool keeps a number of browsers running and hands out isolated incognito
+// contexts that can be used with Screenshot and PrintPDF.
+type Pool struct {
+	config   PoolConfig
+	mutex    sync.Mutex
+	browsers []*poolBrowser
+	closed   bool
+}
+
+// NewPool creates and returns a new pool. The browsers are started in the
+// background.
+func NewPool(config PoolConfig) *Pool {
+	// ensure size
+	config.Size = max(config.Size, 1)
+
+	// start browsers
+	pool := &Pool{config: config}
+	for range config.Size {
+		pool.browsers = append(pool.browsers, startBrowser())
+	}
+
+	return pool
+}
+
+// Acquire will return an incognito browser context from the least busy browser.
+// The context is cancelled when the provided context is done or the configured
+// timeout is reached. The returned function must be called to release the
+// context.
+func (p *Pool) Acquire(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// acquire mutex
+	p.mutex.Lock()
+
+	// check closed
+	if p.closed {
+		p.mutex.Unlock()
+		return nil, nil, ErrPoolClosed.Wrap()
+	}
+
+	// replace crashed browsers
+	for i, b := range p.browsers {
+		if b.crashed() {
+			p.retire(i, true)
+		}
+	}
+
+	// select browser with the fewest active contexts
+	index := 0
+	for i, b := range p.browsers {
+		if b.active < p.browsers[index].active {
+			index = i
+		}
+	}
+	browser := p.browsers[index]
+	browser.active++
+	browser.uses++
+
+	// replace browser if used up
+	if p.config.MaxUses > 0 && browser.uses >= p.config.MaxUses {
+		p.retire(index, true)
+	}
+
+	// release mutex
+	p.mutex.Unlock()
+
+	// await browser
+	select {
+	case <-browser.ready:
+	case <-ctx.Done():
+		p.release(browser)
+		return nil, nil, ctx.Err()
+	}
+
+	// handle start error
+	if browser.err != nil {
+		p.release(browser)
+		return nil, nil, browser.err
+	}
+
+	// create browser context
+	executor := chromedp.FromContext(browser.ctx).Browser
+	id, err := target.CreateBrowserContext().WithDisposeOnDetach(true).Do(cdp.WithExecutor(ctx, executor))
+	if err != nil {
+		p.release(browser)
+		return nil, nil, err
+	}
+
+	// prepare context, tabs are created lazily by the first run
+	tabCtx, tabCancel := chromedp.NewContext(browser.ctx, chromedp.WithExistingBrowserContext(id))
+
+	// cancel browser context with parent context
+	stop := context.AfterFunc(ctx, tabCancel)
+
+	// apply timeout
+	cancel := tabCancel
+	if p.config.Timeout > 0 {
+		tabCtx, cancel = context.WithTimeoutCause(tabCtx, p.config.Timeout, run.ErrTimeLimit)
+	}
+
+	return tabCtx, func() {
+		stop()
+		cancel()
+		tabCancel()
+
+		// dispose browser context
+		disposeCtx, disposeCancel := context.WithTimeout(context.Background(), time.Second)
+		_ = target.DisposeBrowserContext(id).Do(cdp.WithExecutor(disposeCtx, executor))
+		disposeCancel()
+
+		p.release(browser)
+	}, nil
+}
+
+func withContext[T any](p *Pool, ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	// acquire context
+	ctx, cancel, err := p.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer cancel()
+
+	// run function
+	res, err := fn(ctx)
+	if timedOut(ctx) {
+		return res, run.ErrTimeLimit
+	}
+
+	return res, err
+}
+
+// Screenshot will capture a screenshot using a context from the pool.
+func (p *Pool) Screenshot(ctx context.Context, url string, opts ScreenshotOptions) ([]byte, error) {
+	return withContext(p, ctx, func(ctx context.Context) ([]byte, error) {
+		return Screenshot(ctx, url, opts)
+	})
+}
+
+// ScreenshotSet will capture a screenshot per viewport using a context from the
+// pool.
+func (p *Pool) ScreenshotSet(ctx context.Context, url string, opts ScreenshotOptions) ([][]byte, error) {
+	return withContext(p, ctx, func(ctx context.Context) ([][]byte, error) {
+		return ScreenshotSet(ctx, url, opts)
+	})
+}
+
+// Capture will capture a screenshot and inspect the page using a context from
+// the pool.
+func (p *Pool) Capture(ctx context.Context, url string, opts ScreenshotOptions) (*Result, error) {
+	return withContext(p, ctx, func(ctx context.Context) (*Result, error) {
+		return Capture(ctx, url, opts)
+	})
+}
+
+// Inspect will inspect the page using a context from the pool.
+func (p *Pool) Inspect(ctx context.Context, url string, opts ScreenshotOptions) (*Metadata, error) {
+	return withContext(p, ctx, func(ctx context.Context) (*Metadata, error) {
+		return Inspect(ctx, url, opts)
+	})
+}
+
+// PrintPDF will print a PDF using a context from the pool.
+func (p *Pool) PrintPDF(ctx context.Context, url string, opts PDFOptions) ([]byte, error) {
+	return withContext(p, ctx, func(ctx context.Context) ([]byte, error) {
+		return PrintPDF(ctx, url, opts)
+	})
+}
+
+// RenderHTML will render HTML using a context from the pool.
+func (p *Pool) RenderHTML(ctx context.Context, html string, assets fs.FS, opts RenderOptions) ([]byte, error) {
+	return withContext(p, ctx, func(ctx context.Context) ([]byte, error) {
+		return RenderHTML(ctx, html, assets, opts)
+	})
+}
+
+// Close will close the pool. Browsers are stopped once all their contexts have
+// been released.
+func (p *Pool) Close() {
+	// acquire mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// check closed
+	if p.closed {
+		return
+	}
+
+	// retire browsers
+	for i := range p.browsers {
+		p.retire(i, false)
+	}
+	p.browsers = nil
+	p.closed = true
+}
+
+func (p *Pool) retire(index int, replace bool) {
+	// get browser
+	browser := p.browsers[index]
+	browser.retired = true
+
+	// replace browser
+	if replace {
+		p.browsers[index] = startBrowser()
+	}
+
+	// stop idle browser
+	if browser.active == 0 {
+		go browser.stop()
+	}
+}
+
+func (p *Pool) release(browser *poolBrowser) {
+	// acquire mutex
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// decrement active
+	browser.active--
+
+	// stop retired browser if idle
+	if browser.retired && browser.active == 0 {
+		go browser.stop()
+	}
+}
+
+type poolBrowser struct {
+	ready   chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	err     error
+	active  int
+	uses    int
+	retired bool
+}
+
+func startBrowser() *poolBrowser {
+	// prepare browser
+	browser := &poolBrowser{
+		ready: make(chan struct{}),
+	}
+
+	// allocate browser
+	go func() {
+		browser.ctx, browser.cancel, browser.err = Allocate()
+		close(browser.ready)
+	}()
+
+	return browser
+}
+
+func (b *poolBrowser) crashed() bool {
+	// check readiness
+	select {
+	case <-b.ready:
+	default:
+		return false
+	}
+
+	return b.err != nil || b.ctx.Err() != nil
+}
+
+func (b *poolBrowser) stop() {
+	// await browser
+	<-b.ready
+
+	// cancel browser
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
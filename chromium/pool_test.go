@@ -0,0 +1,82 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+func TestPool(t *testing.T) {
+	pool := NewPool(PoolConfig{
+		Size:    2,
+		MaxUses: 2,
+	})
+	defer pool.Close()
+
+	for i := 0; i < 5; i++ {
+		buf, err := pool.Screenshot(nil, "https://example.org", ScreenshotOptions{
+			Width:  800,
+			Height: 600,
+			Scale:  1,
+		})
+		assert.NoError(t, err)
+
+		img, err := png.Decode(bytes.NewReader(buf))
+		assert.NoError(t, err)
+		assert.Equal(t, 800, img.Bounds().Dx())
+	}
+
+	buf, err := pool.PrintPDF(nil, "https://example.org", PDFOptions{})
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf, []byte("%PDF-")))
+}
+
+func TestPoolTimeout(t *testing.T) {
+	pool := NewPool(PoolConfig{
+		Timeout: time.Millisecond,
+	})
+	defer pool.Close()
+
+	_, err := pool.Screenshot(nil, "https://example.org", ScreenshotOptions{
+		Wait: time.Second,
+	})
+	assert.ErrorIs(t, err, run.ErrTimeLimit)
+}
+
+func TestPoolAcquire(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+	defer pool.Close()
+
+	ctx, cancel, err := pool.Acquire(nil)
+	assert.NoError(t, err)
+	defer cancel()
+
+	err = chromedp.Run(ctx, chromedp.Navigate("about:blank"))
+	assert.NoError(t, err)
+
+	targets, err := chromedp.Targets(ctx)
+	assert.NoError(t, err)
+
+	var tabs int
+	for _, info := range targets {
+		if info.Type == "page" && info.BrowserContextID == chromedp.FromContext(ctx).BrowserContextID {
+			tabs++
+		}
+	}
+	assert.Equal(t, 1, tabs)
+}
+
+func TestPoolClosed(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+	pool.Close()
+
+	_, _, err := pool.Acquire(context.Background())
+	assert.True(t, ErrPoolClosed.Is(err))
+}
@@ -0,0 +1,82 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io/fs"
+)
+
+// RenderOrigin is the default origin HTML documents are served from.
+const RenderOrigin = "http://mediakit.local"
+
+// RenderOptions are the options used for rendering HTML.
+type RenderOptions struct {
+	// The options used to capture a screenshot.
+	Screenshot ScreenshotOptions
+
+	// The options used to print a PDF. If set, a PDF is printed instead of
+	// capturing a screenshot.
+	PDF *PDFOptions
+
+	// The origin the document and assets are served from, defaults to
+	// RenderOrigin.
+	Origin string
+}
+
+// RenderHTML will render the given HTML document to a screenshot or PDF. The
+// document is served at the root of the origin and relative references are
+// served from the provided assets. Requests to other origins are blocked
+// unless the screenshot or PDF options configure their own blocking, an empty
+// Blocking allows all requests. A browser context may be provided using
+// Allocate, otherwise a new one will be allocated.
+func RenderHTML(ctx context.Context, html string, assets fs.FS, opts RenderOptions) ([]byte, error) {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// get origin
+	origin := opts.Origin
+	if origin == "" {
+		origin = RenderOrigin
+	}
+
+	// prepare interceptor
+	ic := &interceptor{
+		origin: origin,
+		html:   []byte(html),
+		assets: assets,
+	}
+
+	// print PDF
+	if opts.PDF != nil {
+		pdfOpts := *opts.PDF
+		if pdfOpts.Block == nil {
+			pdfOpts.Block = &Blocking{Offline: true}
+		}
+		return printPDF(ctx, origin+"/", pdfOpts, ic)
+	}
+
+	// block other origins by default
+	if opts.Screenshot.Block == nil {
+		opts.Screenshot.Block = &Blocking{Offline: true}
+	}
+
+	return screenshot(ctx, origin+"/", opts.Screenshot, ic)
+}
+
+// RenderTemplate will execute the template with the provided data and render
+// the result using RenderHTML.
+func RenderTemplate(ctx context.Context, tmpl *template.Template, data any, assets fs.FS, opts RenderOptions) ([]byte, error) {
+	// execute template
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return RenderHTML(ctx, buf.String(), assets, opts)
+}
@@ -0,0 +1,77 @@
+package chromium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAssets = fstest.MapFS{
+	"style.css": &fstest.MapFile{Data: []byte(`body { background: #f00; }`)},
+}
+
+func TestRenderHTML(t *testing.T) {
+	html := `<html><head><link rel="stylesheet" href="style.css"></head><body><h1>Hello</h1></body></html>`
+
+	buf, err := RenderHTML(nil, html, testAssets, RenderOptions{
+		Screenshot: ScreenshotOptions{
+			Width:  400,
+			Height: 300,
+			Scale:  1,
+		},
+	})
+	assert.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, 400, img.Bounds().Dx())
+	r, g, b, _ := img.At(399, 299).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b})
+
+	buf, err = RenderHTML(nil, html, testAssets, RenderOptions{
+		PDF: &PDFOptions{},
+	})
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buf, []byte("%PDF-")))
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("").Parse(`<html><body><h1>{{.}}</h1></body></html>`))
+
+	buf, err := RenderTemplate(nil, tmpl, "Hello", nil, RenderOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+}
+
+func TestInterceptorServe(t *testing.T) {
+	ic := &interceptor{
+		origin: RenderOrigin,
+		html:   []byte("<html></html>"),
+		assets: testAssets,
+	}
+
+	for _, item := range []struct {
+		path   string
+		status int64
+		typ    string
+		body   string
+	}{
+		{"/", 200, "text/html; charset=utf-8", "<html></html>"},
+		{"/style.css", 200, "text/css; charset=utf-8", "body { background: #f00; }"},
+		{"/missing.css", 404, "text/plain", ""},
+		{"/../style.css", 404, "text/plain", ""},
+	} {
+		action := ic.serve("1", item.path).(*fetch.FulfillRequestParams)
+		assert.Equal(t, item.status, action.ResponseCode, item.path)
+		assert.Equal(t, item.typ, action.ResponseHeaders[0].Value, item.path)
+		body, err := base64.StdEncoding.DecodeString(action.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, item.body, string(body), item.path)
+	}
+}
@@ -0,0 +1,222 @@
+package chromium
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// Report is a diagnostic report of a capture.
+type Report struct {
+	// The final URL after redirects.
+	URL string `json:"url"`
+
+	// The console messages, browser log entries and uncaught exceptions by
+	// level (e.g. "log", "info", "warning" or "error").
+	Console map[string][]string `json:"console,omitempty"`
+
+	// The requests that failed or received a status code of 400 or higher.
+	Failed []FailedRequest `json:"failed,omitempty"`
+
+	// The URLs of requests for insecure content from a secure page.
+	MixedContent []string `json:"mixedContent,omitempty"`
+
+	// The total number of bytes transferred.
+	Bytes int64 `json:"bytes"`
+
+	// The time from navigation start until the DOMContentLoaded and load
+	// events of the main frame.
+	DOMContentLoaded time.Duration `json:"domContentLoaded"`
+	Load             time.Duration `json:"load"`
+
+	// Whether console messages, failed requests or mixed content URLs have
+	// been dropped as the report limits were reached.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// The limits of recorded console messages, failed requests and mixed content
+// URLs, the length of console messages and the tracked requests.
+const (
+	maxReportEntries  = 1000
+	maxReportText     = 4096
+	maxReportRequests = 10000
+)
+
+// FailedRequest describes a failed request.
+type FailedRequest struct {
+	URL    string `json:"url"`
+	Type   string `json:"type"`
+	Status int64  `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type reporter struct {
+	mutex    sync.Mutex
+	report   Report
+	messages int
+	requests map[network.RequestID]string
+	start    time.Time
+}
+
+func record(ctx context.Context) *reporter {
+	// prepare reporter
+	r := &reporter{
+		report: Report{
+			Console: map[string][]string{},
+		},
+		requests: map[network.RequestID]string{},
+	}
+
+	// record events
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		r.handle(ctx, ev)
+	})
+
+	return r
+}
+
+func (r *reporter) handle(ctx context.Context, ev interface{}) {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		level := string(ev.Type)
+		if ev.Type == runtime.APITypeAssert {
+			level = "error"
+		}
+		r.log(level, consoleText(ev.Args))
+	case *runtime.EventExceptionThrown:
+		text := ev.ExceptionDetails.Text
+		if ev.ExceptionDetails.Exception != nil && ev.ExceptionDetails.Exception.Description != "" {
+			text = ev.ExceptionDetails.Exception.Description
+		}
+		r.log("error", text)
+	case *log.EventEntryAdded:
+		r.log(string(ev.Entry.Level), ev.Entry.Text)
+	case *network.EventRequestWillBeSent:
+		if len(r.requests) < maxReportRequests {
+			r.requests[ev.RequestID] = ev.Request.URL
+		}
+		if r.start.IsZero() && ev.Type == network.ResourceTypeDocument && ev.FrameID == mainFrame(ctx) && ev.Timestamp != nil {
+			r.start = ev.Timestamp.Time()
+		}
+		if ev.Request.MixedContentType != "" && ev.Request.MixedContentType != security.MixedContentTypeNone {
+			if len(r.report.MixedContent) < maxReportEntries {
+				r.report.MixedContent = append(r.report.MixedContent, ev.Request.URL)
+			} else {
+				r.report.Truncated = true
+			}
+		}
+	case *network.EventResponseReceived:
+		if ev.Response.Status >= 400 {
+			r.fail(FailedRequest{
+				URL:    ev.Response.URL,
+				Type:   string(ev.Type),
+				Status: ev.Response.Status,
+			})
+		}
+	case *network.EventLoadingFinished:
+		r.report.Bytes += int64(ev.EncodedDataLength)
+		delete(r.requests, ev.RequestID)
+	case *network.EventLoadingFailed:
+		r.fail(FailedRequest{
+			URL:   r.requests[ev.RequestID],
+			Type:  string(ev.Type),
+			Error: ev.ErrorText,
+		})
+		delete(r.requests, ev.RequestID)
+	case *page.EventFrameNavigated:
+		if ev.Frame.ParentID == "" {
+			r.report.URL = ev.Frame.URL + ev.Frame.URLFragment
+		}
+	case *page.EventDomContentEventFired:
+		r.report.DOMContentLoaded = r.since(ev.Timestamp)
+	case *page.EventLoadEventFired:
+		r.report.Load = r.since(ev.Timestamp)
+	}
+}
+
+func (r *reporter) log(level, text string) {
+	// check limit
+	if r.messages >= maxReportEntries {
+		r.report.Truncated = true
+		return
+	}
+
+	// cut text
+	if len(text) > maxReportText {
+		text = strings.ToValidUTF8(text[:maxReportText], "")
+	}
+
+	// add message
+	r.report.Console[level] = append(r.report.Console[level], text)
+	r.messages++
+}
+
+func (r *reporter) fail(failed FailedRequest) {
+	// check limit
+	if len(r.report.Failed) >= maxReportEntries {
+		r.report.Truncated = true
+		return
+	}
+
+	// add request
+	r.report.Failed = append(r.report.Failed, failed)
+}
+
+func (r *reporter) since(ts *cdp.MonotonicTime) time.Duration {
+	// check times
+	if ts == nil || r.start.IsZero() {
+		return 0
+	}
+
+	return ts.Time().Sub(r.start)
+}
+
+func (r *reporter) result() *Report {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// copy report
+	report := r.report
+	report.Console = map[string][]string{}
+	for level, messages := range r.report.Console {
+		report.Console[level] = append([]string{}, messages...)
+	}
+	report.Failed = append([]FailedRequest(nil), r.report.Failed...)
+	report.MixedContent = append([]string(nil), r.report.MixedContent...)
+
+	return &report
+}
+
+func consoleText(args []*runtime.RemoteObject) string {
+	// format arguments
+	var parts []string
+	for _, arg := range args {
+		var str string
+		if len(arg.Value) > 0 && json.Unmarshal(arg.Value, &str) == nil {
+			parts = append(parts, str)
+		} else if len(arg.Value) > 0 {
+			parts = append(parts, string(arg.Value))
+		} else if arg.Description != "" {
+			parts = append(parts, arg.Description)
+		} else {
+			parts = append(parts, string(arg.Type))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,152 @@
+package chromium
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporter(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	ts := func(d time.Duration) *cdp.MonotonicTime {
+		t := cdp.MonotonicTime(start.Add(d))
+		return &t
+	}
+
+	r := &reporter{
+		report: Report{
+			Console: map[string][]string{},
+		},
+		requests: map[network.RequestID]string{},
+	}
+
+	for _, ev := range []interface{}{
+		&network.EventRequestWillBeSent{
+			RequestID: "1",
+			Type:      network.ResourceTypeDocument,
+			Timestamp: ts(0),
+			Request:   &network.Request{URL: "https://example.org/"},
+		},
+		&network.EventRequestWillBeSent{
+			RequestID: "2",
+			Type:      network.ResourceTypeImage,
+			Timestamp: ts(time.Millisecond),
+			Request:   &network.Request{URL: "http://example.org/image.png", MixedContentType: "blockable"},
+		},
+		&network.EventRequestWillBeSent{
+			RequestID: "3",
+			Type:      network.ResourceTypeScript,
+			Timestamp: ts(time.Millisecond),
+			Request:   &network.Request{URL: "https://example.org/app.js"},
+		},
+		&network.EventLoadingFinished{RequestID: "1", EncodedDataLength: 1000},
+		&network.EventLoadingFailed{RequestID: "2", Type: network.ResourceTypeImage, ErrorText: "net::ERR_BLOCKED_BY_CLIENT"},
+		&network.EventResponseReceived{RequestID: "3", Type: network.ResourceTypeScript, Response: &network.Response{
+			URL:    "https://example.org/app.js",
+			Status: 404,
+		}},
+		&network.EventLoadingFinished{RequestID: "3", EncodedDataLength: 200},
+		&runtime.EventConsoleAPICalled{Type: runtime.APITypeLog, Args: []*runtime.RemoteObject{
+			{Type: "string", Value: []byte(`"count:"`)},
+			{Type: "number", Value: []byte(`42`)},
+			{Type: "object", Description: "Object"},
+		}},
+		&runtime.EventExceptionThrown{ExceptionDetails: &runtime.ExceptionDetails{
+			Text:      "Uncaught",
+			Exception: &runtime.RemoteObject{Description: "Error: failed"},
+		}},
+		&log.EventEntryAdded{Entry: &log.Entry{Level: log.LevelWarning, Text: "deprecated"}},
+		&page.EventFrameNavigated{Frame: &cdp.Frame{URL: "https://example.org/"}},
+		&page.EventDomContentEventFired{Timestamp: ts(100 * time.Millisecond)},
+		&page.EventLoadEventFired{Timestamp: ts(250 * time.Millisecond)},
+	} {
+		r.handle(ctx, ev)
+	}
+
+	assert.Equal(t, &Report{
+		URL: "https://example.org/",
+		Console: map[string][]string{
+			"log":     {"count: 42 Object"},
+			"error":   {"Error: failed"},
+			"warning": {"deprecated"},
+		},
+		Failed: []FailedRequest{
+			{URL: "http://example.org/image.png", Type: "Image", Error: "net::ERR_BLOCKED_BY_CLIENT"},
+			{URL: "https://example.org/app.js", Type: "Script", Status: 404},
+		},
+		MixedContent:     []string{"http://example.org/image.png"},
+		Bytes:            1200,
+		DOMContentLoaded: 100 * time.Millisecond,
+		Load:             250 * time.Millisecond,
+	}, r.result())
+	assert.Empty(t, r.requests)
+}
+
+func TestReporterLimits(t *testing.T) {
+	r := &reporter{
+		report: Report{
+			Console: map[string][]string{},
+		},
+		requests: map[network.RequestID]string{},
+	}
+
+	for i := 0; i < maxReportEntries+10; i++ {
+		r.handle(context.Background(), &runtime.EventConsoleAPICalled{Type: runtime.APITypeLog, Args: []*runtime.RemoteObject{
+			{Type: "string", Value: []byte(`"` + strings.Repeat("x", maxReportText+10) + `"`)},
+		}})
+		r.handle(context.Background(), &network.EventLoadingFailed{RequestID: network.RequestID(strconv.Itoa(i)), ErrorText: "failed"})
+	}
+
+	report := r.result()
+	assert.True(t, report.Truncated)
+	assert.Len(t, report.Console["log"], maxReportEntries)
+	assert.Len(t, report.Console["log"][0], maxReportText)
+	assert.Len(t, report.Failed, maxReportEntries)
+}
+
+func TestCaptureReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<html><body>
+			<img src="/missing.png">
+			<script>console.log("hello", 42); console.warn("careful");</script>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	res, err := Capture(nil, server.URL, ScreenshotOptions{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Image)
+	assert.Equal(t, server.URL+"/", res.Report.URL)
+	assert.Equal(t, []string{"hello 42"}, res.Report.Console["log"])
+	assert.Equal(t, []string{"careful"}, res.Report.Console["warning"])
+	assert.Equal(t, []FailedRequest{
+		{URL: server.URL + "/missing.png", Type: "Image", Status: 404},
+	}, res.Report.Failed)
+	assert.NotZero(t, res.Report.Bytes)
+	assert.NotZero(t, res.Report.DOMContentLoaded)
+	assert.True(t, res.Report.Load >= res.Report.DOMContentLoaded)
+
+	res, err = Capture(nil, server.URL, ScreenshotOptions{
+		Pedantic: true,
+	})
+	assert.Error(t, err)
+	assert.Empty(t, res.Image)
+	assert.Equal(t, server.URL+"/", res.Report.URL)
+	assert.Equal(t, []string{"hello 42"}, res.Report.Console["log"])
+}
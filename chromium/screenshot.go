@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/256dpi/xo"
@@ -12,6 +15,8 @@ import (
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
+
+	"github.com/256dpi/mediakit/run"
 )
 
 const scrollThrough = `
@@ -25,7 +30,6 @@ new Promise((resolve) => {
 			window.scrollTo(0, scrolls * step);
 
 			const total = document.body.scrollHeight / step;
-			console.log(step, frequency, total, scrolls);
 
 			if (scrolls < total) {
 				scrolls += 1;
@@ -44,6 +48,16 @@ new Promise((resolve) => {
 // before running tests or by setting the environment variable MK_NOSBX to 1.
 var NoSandbox = os.Getenv("MK_NOSBX") == "1"
 
+// ExecPath is the path of the browser binary. If empty, the binary is looked
+// up in the well-known locations.
+var ExecPath = ""
+
+// Limits are the resource limits applied to allocated browsers and captures.
+// The thread limit caps the raster threads and the memory limit caps the
+// JavaScript heap of each renderer, as the browser does not run with a capped
+// address space.
+var Limits run.Limits
+
 // Allocate will allocate a new browser instance and return an associated
 // context and cancel function.
 func Allocate() (context.Context, context.CancelFunc, error) {
@@ -55,6 +69,21 @@ func Allocate() (context.Context, context.CancelFunc, error) {
 	if NoSandbox {
 		execOpts = append(execOpts, chromedp.NoSandbox)
 	}
+	if ExecPath != "" {
+		execOpts = append(execOpts, chromedp.ExecPath(ExecPath))
+	}
+	if Limits.Threads > 0 {
+		execOpts = append(execOpts, chromedp.Flag("num-raster-threads", strconv.Itoa(Limits.Threads)))
+	}
+	if Limits.Memory > 0 {
+		execOpts = append(execOpts, chromedp.Flag("js-flags", fmt.Sprintf("--max-old-space-size=%d", Limits.Memory/1024/1024)))
+	}
+	if Limits.Nice != 0 {
+		execOpts = append(execOpts, chromedp.ModifyCmdFunc(func(cmd *exec.Cmd) {
+			cmd.Args = run.Limits{Nice: Limits.Nice}.Wrap(cmd.Args)
+			cmd.Path, _ = exec.LookPath(cmd.Args[0])
+		}))
+	}
 	ctx, cancel1 := chromedp.NewExecAllocator(ctx, execOpts...)
 
 	// wrap context context
@@ -82,96 +111,403 @@ type ScreenshotOptions struct {
 	Scale    float64
 	Pedantic bool
 	Wait     time.Duration
+
+	// The image format, defaults to PNG, and the quality (1-100) used for
+	// JPEG and WebP.
+	Format  Format
+	Quality int
+
+	// Whether full page screenshots are captured in viewport sized tiles that
+	// are joined using vips. Tiles are used automatically for pages taller
+	// than MaxCaptureHeight. Fixed elements are repeated in every tile. Pages
+	// taller than MaxPageHeight or the format limit are cut.
+	Tiled bool
+
+	// The conditions awaited in order before capturing and their overall
+	// deadline, defaults to DefaultWaitTimeout.
+	WaitFor     []Condition
+	WaitTimeout time.Duration
+
+	// The element to capture by CSS selector.
+	Selector string
+
+	// The region to capture, takes precedence over Selector.
+	Clip *Clip
+
+	// The elements to hide or remove by CSS selector.
+	Hide   []string
+	Remove []string
+
+	// The CSS to inject and the script to run before capturing. The script
+	// may return a promise that is awaited.
+	CSS    string
+	Script string
+
+	// The emulated color scheme ("light" or "dark"), media type ("screen" or
+	// "print") and whether reduced motion is preferred.
+	ColorScheme   string
+	Media         string
+	ReducedMotion bool
+
+	// Whether to emulate a mobile device with touch support.
+	Mobile bool
+
+	// The emulated user agent, timezone (e.g. "Europe/Zurich"), locale
+	// (e.g. "de-CH") and geolocation.
+	UserAgent   string
+	Timezone    string
+	Locale      string
+	Geolocation *Geolocation
+
+	// The extra HTTP headers sent with requests to the captured URL's origin
+	// and the cookies set before navigating.
+	Headers map[string]string
+	Cookies []Cookie
+
+	// The credentials provided to basic auth challenges of the captured
+	// URL's origin.
+	BasicAuth *Credentials
+
+	// The requests to block.
+	Block *Blocking
+
+	// The guard applied to the URL, redirects and subrequests.
+	Guard *Guard
+
+	// Whether to inspect the page metadata when using Capture.
+	Inspect bool
+
+	// Whether to archive the page as an MHTML snapshot and a WARC record set
+	// of the network responses when using Capture. Cookie and authorization
+	// headers are omitted from the WARC records. At most 1000 requests and
+	// 64 MiB of response bodies are archived, further bodies are truncated.
+	Snapshot bool
+	WARC     bool
+
+	// The viewports to capture when using Capture or ScreenshotSet. The page
+	// is loaded once using the first viewport, which overrides the width,
+	// height, scale and mobile options and the user agent if not set.
+	Viewports []Viewport
+}
+
+// Result is the result of a capture.
+type Result struct {
+	// The captured screenshot.
+	Image []byte
+
+	// The captured screenshots per viewport, if configured, instead of Image.
+	Images [][]byte
+
+	// The page metadata, if inspected.
+	Metadata *Metadata
+
+	// The diagnostic report.
+	Report *Report
+
+	// The MHTML snapshot and WARC records, if archived.
+	Snapshot []byte
+	WARC     []byte
 }
 
 // Screenshot will capture a screenshot of the given URL. A browser context may
 // be provided using Allocate, otherwise a new one will be allocated.
 func Screenshot(ctx context.Context, url string, opts ScreenshotOptions) ([]byte, error) {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return screenshot(ctx, url, opts, nil)
+}
+
+// Capture will capture a screenshot of the given URL and optionally inspect
+// the page metadata in the same browser session. The result includes a
+// diagnostic report of the capture. If the capture fails after loading
+// started, a partial result with the report is returned together with the
+// error. A browser context may be provided using Allocate, otherwise a new one
+// will be allocated.
+func Capture(ctx context.Context, url string, opts ScreenshotOptions) (*Result, error) {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	return capture(ctx, url, opts, nil, true)
+}
+
+func screenshot(ctx context.Context, url string, opts ScreenshotOptions, ic *interceptor) ([]byte, error) {
+	// capture a single screenshot
+	opts.Viewports = nil
+
+	// capture screenshot
+	res, err := capture(ctx, url, opts, ic, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Image, nil
+}
+
+func capture(ctx context.Context, url string, opts ScreenshotOptions, ic *interceptor, image bool) (*Result, error) {
+	// check format
+	if !opts.Format.Valid() {
+		return nil, fmt.Errorf("invalid format: %s", opts.Format)
+	}
+
+	// collect errors, track requests and record report
+	logErrors := collectErrors(ctx)
+	requests := track(ctx)
+	reports := record(ctx)
+
+	// collect responses
+	var responses *archiver
+	if opts.WARC {
+		responses = collectResponses(ctx)
+	}
+
+	// prepare interception
+	ic, err := intercept(ctx, ic, url, opts.BasicAuth, opts.Headers, opts.Block, opts.Guard)
+	if err != nil {
+		return nil, err
+	}
+
+	// use first viewport
+	if len(opts.Viewports) > 0 {
+		opts = opts.Viewports[0].apply(opts)
+	}
+
+	// load page
+	var res Result
+	tasks := chromedp.Tasks{
+		withTimeout(10*time.Second, "emulation failed", emulate(opts)),
+		withTimeout(10*time.Second, "session setup failed", session(url, opts.Cookies)),
+		withTimeout(20*time.Second, "navigation failed", chromedp.Navigate(url)),
+		withTimeout(20*time.Second, "awaiting body failed", chromedp.WaitReady("body")),
+		wait(opts.WaitFor, opts.WaitTimeout, requests),
+	}
+
+	// inspect page
+	if opts.Inspect || !image {
+		tasks = append(tasks, withTimeout(10*time.Second, "inspection failed", inspect(&res.Metadata)))
+	}
+
+	// capture screenshots
+	if image && len(opts.Viewports) > 0 {
+		tasks = append(tasks, withTimeout(opts.Wait+20*time.Second, "screenshot failed", prepare(opts)))
+		res.Images = make([][]byte, len(opts.Viewports))
+		for i, vp := range opts.Viewports {
+			vpOpts := vp.apply(opts)
+			if i > 0 {
+				tasks = append(tasks,
+					withTimeout(20*time.Second, "layout failed", relayout(vpOpts)),
+					wait(vpOpts.WaitFor, vpOpts.WaitTimeout, requests),
+				)
+			}
+			tasks = append(tasks, withTimeout(20*time.Second, "screenshot failed", shoot(vpOpts, &res.Images[i])))
+		}
+	} else if image {
+		tasks = append(tasks, withTimeout(opts.Wait+20*time.Second, "screenshot failed", prepare(opts), shoot(opts, &res.Image)))
+	}
+
+	// archive page
+	if opts.Snapshot {
+		tasks = append(tasks, withTimeout(20*time.Second, "snapshot failed", snapshot(&res.Snapshot)))
+	}
+	if opts.WARC {
+		tasks = append(tasks, withTimeout(20*time.Second, "archiving failed", archive(responses, &res.WARC)))
+	}
+
+	// run tasks
+	err = chromedp.Run(ctx, tasks)
+
+	// prepare partial result with the report for failed captures
+	partial := &Result{Report: reports.result()}
+
+	// check errors
+	if blocked := ic.err(); blocked != nil {
+		return partial, blocked
+	} else if timedOut(ctx) {
+		return partial, run.ErrTimeLimit
+	} else if err != nil {
+		return partial, err
+	}
+
+	// check output
+	size := len(res.Image) + len(res.Snapshot) + len(res.WARC)
+	for _, img := range res.Images {
+		size += len(img)
+	}
+	if Limits.Output > 0 && int64(size) > Limits.Output {
+		return partial, run.ErrOutputLimit
+	}
+
+	// handle log errors
+	if opts.Pedantic && len(logErrors()) > 0 {
+		return partial, fmt.Errorf("log errors: %s", logErrors())
+	}
+
+	// set report
+	res.Report = partial.Report
+
+	return &res, nil
+}
+
+func scroll() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// scroll through page
+		err := chromedp.Evaluate(scrollThrough, nil, func(params *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return params.WithAwaitPromise(true)
+		}).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// scroll back to top
+		return chromedp.Evaluate(`window.scroll({top: 0})`, nil).Do(ctx)
+	})
+}
+
+func prepare(opts ScreenshotOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// scroll through page once
+		if opts.Full {
+			err := scroll().Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		// wait some time
+		if opts.Wait > 0 {
+			time.Sleep(opts.Wait)
+		}
+
+		// inject style and script
+		return inject(opts).Do(ctx)
+	})
+}
+
+func shoot(opts ScreenshotOptions, image *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// get height
+		var height int64
+		err := chromedp.Evaluate(`document.body.scrollHeight`, &height).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// get clip
+		region, err := clip(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		// check region height
+		if region != nil && region.Height > float64(maxHeight(opts)) {
+			return fmt.Errorf("region too tall: %.0fpx", region.Height)
+		}
+
+		// cut page height
+		if opts.Full && region == nil && height > maxHeight(opts) {
+			height = maxHeight(opts)
+			if !tiled(opts, height) {
+				var width float64
+				err = chromedp.Evaluate(`document.documentElement.clientWidth`, &width).Do(ctx)
+				if err != nil {
+					return err
+				}
+				region = &page.Viewport{Width: width, Height: float64(height), Scale: 1}
+			}
+		}
+
+		// capture tiles
+		if opts.Full && region == nil && tiled(opts, height) {
+			*image, err = captureTiles(ctx, opts, height)
+			return err
+		}
+
+		// prepare params
+		params := page.CaptureScreenshot().
+			WithFormat(opts.Format.capture()).
+			WithCaptureBeyondViewport((opts.Full && height > opts.Height) || region != nil).
+			WithClip(region)
+		if opts.Quality > 0 && opts.Format != "" && opts.Format != PNG {
+			params = params.WithQuality(int64(opts.Quality))
+		}
+
+		// capture screenshot
+		*image, err = params.Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func openTab(ctx context.Context) (context.Context, context.CancelFunc, error) {
 	// ensure context
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
 	// ensure allocation context
+	var cancels []context.CancelFunc
 	if chromedp.FromContext(ctx) == nil {
 		var cancel context.CancelFunc
 		var err error
 		ctx, cancel, err = Allocate()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		defer cancel()
+		cancels = append(cancels, cancel)
 	}
 
 	// wrap context
 	ctx, cancel := chromedp.NewContext(ctx)
-	defer cancel()
+	cancels = append(cancels, cancel)
 
+	// apply timeout
+	if Limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, Limits.Timeout, run.ErrTimeLimit)
+		cancels = append(cancels, cancel)
+	}
+
+	return ctx, func() {
+		for i := len(cancels) - 1; i >= 0; i-- {
+			cancels[i]()
+		}
+	}, nil
+}
+
+func timedOut(ctx context.Context) bool {
+	// check for an applied time limit rather than any deadline
+	return errors.Is(context.Cause(ctx), run.ErrTimeLimit)
+}
+
+func collectErrors(ctx context.Context) func() []string {
 	// collect errors
+	var mutex sync.Mutex
 	var logErrors []string
 	chromedp.ListenTarget(ctx, func(ev interface{}) {
 		if ev, ok := ev.(*log.EventEntryAdded); ok {
 			if ev.Entry.Level == log.LevelError {
+				mutex.Lock()
 				logErrors = append(logErrors, fmt.Sprintf("%s (%s)", ev.Entry.Text, ev.Entry.URL))
+				mutex.Unlock()
 			}
 		}
 	})
 
-	// capture screenshot
-	var buf []byte
-	err := chromedp.Run(ctx,
-		withTimeout(10*time.Second, "emulation failed", chromedp.EmulateViewport(opts.Width, opts.Height, chromedp.EmulateScale(opts.Scale))),
-		withTimeout(20*time.Second, "navigation failed", chromedp.Navigate(url)),
-		withTimeout(20*time.Second, "awaiting body failed", chromedp.WaitReady("body")),
-		withTimeout(opts.Wait+20*time.Second, "screenshot failed", chromedp.ActionFunc(func(ctx context.Context) error {
-			// scroll through page once
-			if opts.Full {
-				err := chromedp.Evaluate(scrollThrough, nil, func(params *runtime.EvaluateParams) *runtime.EvaluateParams {
-					return params.WithAwaitPromise(true)
-				}).Do(ctx)
-				if err != nil {
-					return err
-				}
-				err = chromedp.Evaluate(`window.scroll({top: 0})`, nil).Do(ctx)
-				if err != nil {
-					return err
-				}
-			}
-
-			// wait some time
-			if opts.Wait > 0 {
-				time.Sleep(opts.Wait)
-			}
-
-			// get height
-			var height int64
-			err := chromedp.Evaluate(`document.body.scrollHeight`, &height).Do(ctx)
-			if err != nil {
-				return err
-			}
-
-			// capture screenshot
-			buf, err = page.CaptureScreenshot().
-				WithFormat(page.CaptureScreenshotFormatPng).
-				WithCaptureBeyondViewport(opts.Full && height > opts.Height).
-				Do(ctx)
-			if err != nil {
-				return err
-			}
-
-			return nil
-		})),
-	)
-	if err != nil {
-		return nil, err
+	return func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return logErrors
 	}
-
-	// handle log errors
-	if opts.Pedantic && len(logErrors) > 0 {
-		return nil, fmt.Errorf("log errors: %s", logErrors)
-	}
-
-	return buf, nil
 }
 
 func withTimeout(timeout time.Duration, msg string, tasks ...chromedp.Action) chromedp.ActionFunc {
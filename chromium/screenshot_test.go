@@ -2,12 +2,17 @@ package chromium
 
 import (
 	"bytes"
+	"context"
 	"image"
+	"image/color"
 	"image/png"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/run"
 )
 
 func TestScreenshot(t *testing.T) {
@@ -46,3 +51,137 @@ func TestScreenshot(t *testing.T) {
 		Max: image.Point{X: 3840, Y: 2160},
 	}, img.Bounds())
 }
+
+func TestScreenshotOptions(t *testing.T) {
+	html := `<html>
+	<head><style>
+		body { margin: 0; background: #fff; }
+		#box { position: absolute; left: 10px; top: 20px; width: 100px; height: 50px; background: #f00; }
+		@media (prefers-color-scheme: dark) { body { background: #000; } }
+	</style></head>
+	<body><div id="box"></div></body>
+</html>`
+
+	red := color.RGBA{R: 0xff, A: 0xff}
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	black := color.RGBA{A: 0xff}
+
+	for _, item := range []struct {
+		name   string
+		opts   ScreenshotOptions
+		width  int
+		height int
+		pixel  color.RGBA
+	}{
+		{
+			name:   "Selector",
+			opts:   ScreenshotOptions{Selector: "#box"},
+			width:  100,
+			height: 50,
+			pixel:  red,
+		},
+		{
+			name:   "Clip",
+			opts:   ScreenshotOptions{Clip: &Clip{X: 0, Y: 0, Width: 40, Height: 30}},
+			width:  40,
+			height: 30,
+			pixel:  white,
+		},
+		{
+			name:   "Hide",
+			opts:   ScreenshotOptions{Selector: "#box", Hide: []string{"#box"}},
+			width:  100,
+			height: 50,
+			pixel:  white,
+		},
+		{
+			name: "DarkMode",
+			opts: ScreenshotOptions{
+				Clip:          &Clip{X: 0, Y: 0, Width: 5, Height: 5},
+				ColorScheme:   "dark",
+				ReducedMotion: true,
+				Media:         "screen",
+			},
+			width:  5,
+			height: 5,
+			pixel:  black,
+		},
+	} {
+		t.Run(item.name, func(t *testing.T) {
+			opts := item.opts
+			opts.Width = 400
+			opts.Height = 300
+			opts.Scale = 1
+
+			buf, err := RenderHTML(nil, html, nil, RenderOptions{
+				Screenshot: opts,
+			})
+			assert.NoError(t, err)
+
+			img, err := png.Decode(bytes.NewReader(buf))
+			assert.NoError(t, err)
+			assert.Equal(t, item.width, img.Bounds().Dx())
+			assert.Equal(t, item.height, img.Bounds().Dy())
+			assert.Equal(t, item.pixel, color.RGBAModel.Convert(img.At(1, 1)))
+		})
+	}
+}
+
+func TestScreenshotEmulation(t *testing.T) {
+	checks := []string{
+		`navigator.userAgent === "mediakit"`,
+		`Intl.DateTimeFormat().resolvedOptions().timeZone === "Europe/Zurich"`,
+		`navigator.language === "de-CH"`,
+		`navigator.maxTouchPoints > 0`,
+		`matchMedia("(prefers-reduced-motion: reduce)").matches`,
+		`coords !== null && coords.latitude === 47.37 && coords.longitude === 8.54`,
+	}
+
+	script := `new Promise((resolve) => {
+		navigator.geolocation.getCurrentPosition((pos) => resolve(pos.coords), () => resolve(null));
+	}).then((coords) => {
+		const checks = [` + strings.Join(checks, ", ") + `];
+		document.body.innerHTML = checks.map((ok) => {
+			return '<div style="height: 10px; background: ' + (ok ? '#0f0' : '#f00') + '"></div>';
+		}).join("");
+	})`
+
+	buf, err := RenderHTML(nil, `<html><body style="margin: 0"></body></html>`, nil, RenderOptions{
+		Origin: "https://mediakit.local",
+		Screenshot: ScreenshotOptions{
+			Width:         400,
+			Height:        300,
+			Scale:         1,
+			ReducedMotion: true,
+			Mobile:        true,
+			UserAgent:     "mediakit",
+			Timezone:      "Europe/Zurich",
+			Locale:        "de-CH",
+			Geolocation:   &Geolocation{Latitude: 47.37, Longitude: 8.54, Accuracy: 10},
+			Script:        script,
+		},
+	})
+	assert.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(buf))
+	assert.NoError(t, err)
+
+	green := color.RGBA{G: 0xff, A: 0xff}
+	for i, check := range checks {
+		assert.Equal(t, green, color.RGBAModel.Convert(img.At(1, i*10+5)), check)
+	}
+}
+
+func TestTimedOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.False(t, timedOut(ctx))
+
+	ctx, cancel = context.WithTimeoutCause(context.Background(), time.Millisecond, run.ErrTimeLimit)
+	defer cancel()
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	<-child.Done()
+	assert.True(t, timedOut(child))
+}
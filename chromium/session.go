@@ -0,0 +1,48 @@
+package chromium
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Cookie defines a cookie set before navigating. If the domain is empty, the
+// cookie is set for the captured URL.
+type Cookie struct {
+	Name     string
+	Value    string
+	Domain   string
+	Path     string
+	Secure   bool
+	HTTPOnly bool
+}
+
+// Credentials define HTTP basic auth credentials.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+func session(url string, cookies []Cookie) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// set cookies
+		for _, cookie := range cookies {
+			params := network.SetCookie(cookie.Name, cookie.Value).
+				WithPath(cookie.Path).
+				WithSecure(cookie.Secure).
+				WithHTTPOnly(cookie.HTTPOnly)
+			if cookie.Domain != "" {
+				params = params.WithDomain(cookie.Domain)
+			} else {
+				params = params.WithURL(url)
+			}
+			err := params.Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,163 @@
+package chromium
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScreenshotSession(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r)
+		mutex.Unlock()
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte("<html><body>Hello</body></html>"))
+	}))
+	defer server.Close()
+
+	buf, err := Screenshot(nil, server.URL, ScreenshotOptions{
+		UserAgent: "mediakit",
+		Headers: map[string]string{
+			"X-Token": "token",
+		},
+		Cookies: []Cookie{
+			{Name: "session", Value: "1234"},
+		},
+		BasicAuth: &Credentials{
+			Username: "user",
+			Password: "secret",
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if assert.NotEmpty(t, requests) {
+		last := requests[len(requests)-1]
+		assert.Equal(t, "mediakit", last.UserAgent())
+		assert.Equal(t, "token", last.Header.Get("X-Token"))
+		cookie, err := last.Cookie("session")
+		assert.NoError(t, err)
+		assert.Equal(t, "1234", cookie.Value)
+	}
+}
+
+func TestPrintPDFSession(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r)
+		mutex.Unlock()
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte("<html><body>Hello</body></html>"))
+	}))
+	defer server.Close()
+
+	buf, err := PrintPDF(nil, server.URL, PDFOptions{
+		Headers: map[string]string{
+			"X-Token": "token",
+		},
+		Cookies: []Cookie{
+			{Name: "session", Value: "1234"},
+		},
+		BasicAuth: &Credentials{
+			Username: "user",
+			Password: "secret",
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if assert.NotEmpty(t, requests) {
+		last := requests[len(requests)-1]
+		assert.Equal(t, "token", last.Header.Get("X-Token"))
+		cookie, err := last.Cookie("session")
+		assert.NoError(t, err)
+		assert.Equal(t, "1234", cookie.Value)
+		_, _, ok := last.BasicAuth()
+		assert.True(t, ok)
+	}
+}
+
+func TestInterceptorAuthenticate(t *testing.T) {
+	ic := &interceptor{
+		pageOrigin: "https://example.org",
+		auth:       &Credentials{Username: "user", Password: "secret"},
+	}
+
+	event := func(id fetch.RequestID, origin string) *fetch.EventAuthRequired {
+		return &fetch.EventAuthRequired{
+			RequestID:     id,
+			AuthChallenge: &fetch.AuthChallenge{Origin: origin},
+		}
+	}
+
+	action := ic.authenticate(event("1", "https://example.org")).(*fetch.ContinueWithAuthParams)
+	assert.Equal(t, &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: "user",
+		Password: "secret",
+	}, action.AuthChallengeResponse)
+
+	action = ic.authenticate(event("1", "https://example.org")).(*fetch.ContinueWithAuthParams)
+	assert.Equal(t, fetch.AuthChallengeResponseResponseCancelAuth, action.AuthChallengeResponse.Response)
+
+	action = ic.authenticate(event("2", "https://example.com")).(*fetch.ContinueWithAuthParams)
+	assert.Equal(t, fetch.AuthChallengeResponseResponseCancelAuth, action.AuthChallengeResponse.Response)
+}
+
+func TestInterceptorHeaders(t *testing.T) {
+	ic := &interceptor{
+		pageOrigin: "https://example.org",
+		headers:    map[string]string{"X-Token": "token"},
+	}
+
+	event := func(url string) *fetch.EventRequestPaused {
+		return &fetch.EventRequestPaused{
+			RequestID: "1",
+			Request: &network.Request{
+				URL: url,
+				Headers: network.Headers{
+					"Accept":  "*/*",
+					"x-token": "other",
+				},
+			},
+		}
+	}
+
+	action := ic.handle(context.Background(), event("https://example.org/app.js")).(*fetch.ContinueRequestParams)
+	assert.Equal(t, []*fetch.HeaderEntry{
+		{Name: "Accept", Value: "*/*"},
+		{Name: "X-Token", Value: "token"},
+	}, action.Headers)
+
+	action = ic.handle(context.Background(), event("https://example.com/app.js")).(*fetch.ContinueRequestParams)
+	assert.Empty(t, action.Headers)
+}
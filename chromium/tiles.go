@@ -0,0 +1,178 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+
+	"github.com/256dpi/mediakit/vips"
+)
+
+// Format is a screenshot image format.
+type Format string
+
+// The available formats.
+const (
+	PNG  Format = "png"
+	JPEG Format = "jpeg"
+	WebP Format = "webp"
+)
+
+// Valid returns whether the format is valid.
+func (f Format) Valid() bool {
+	return f == "" || f == PNG || f == JPEG || f == WebP
+}
+
+func (f Format) capture() page.CaptureScreenshotFormat {
+	switch f {
+	case JPEG:
+		return page.CaptureScreenshotFormatJpeg
+	case WebP:
+		return page.CaptureScreenshotFormatWebp
+	default:
+		return page.CaptureScreenshotFormatPng
+	}
+}
+
+func (f Format) suffix(quality int) string {
+	// get extension
+	var ext string
+	switch f {
+	case JPEG:
+		ext = ".jpg"
+	case WebP:
+		ext = ".webp"
+	default:
+		return ".png"
+	}
+
+	// add quality
+	if quality > 0 {
+		ext += "[Q=" + strconv.Itoa(quality) + "]"
+	}
+
+	return ext
+}
+
+// MaxCaptureHeight is the height in device pixels above which full page
+// screenshots are captured in tiles.
+var MaxCaptureHeight int64 = 16384
+
+// MaxPageHeight is the height in device pixels at which full page screenshots
+// are cut. JPEG and WebP screenshots are additionally cut at the maximum height
+// supported by the format.
+var MaxPageHeight int64 = 131072
+
+const awaitFrame = `
+new Promise((resolve) => {
+	requestAnimationFrame(() => requestAnimationFrame(() => resolve(true)));
+});
+`
+
+func (f Format) maxHeight() int64 {
+	switch f {
+	case JPEG:
+		return 65535
+	case WebP:
+		return 16383
+	default:
+		return math.MaxInt64
+	}
+}
+
+func maxHeight(opts ScreenshotOptions) int64 {
+	// get scale
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+
+	return int64(float64(min(MaxPageHeight, opts.Format.maxHeight())) / scale)
+}
+
+func tiled(opts ScreenshotOptions, height int64) bool {
+	// get scale
+	scale := opts.Scale
+	if scale <= 0 {
+		scale = 1
+	}
+
+	return opts.Tiled || int64(float64(height)*scale) > MaxCaptureHeight
+}
+
+func captureTiles(ctx context.Context, opts ScreenshotOptions, height int64) ([]byte, error) {
+	// check viewport
+	if opts.Height <= 0 {
+		return nil, fmt.Errorf("tiled capture requires a viewport height")
+	}
+
+	// create temporary directory
+	dir, err := os.MkdirTemp("", "chromium-tiles-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// capture tiles
+	var files []string
+	for y := int64(0); y < height; y += opts.Height {
+		// shrink viewport for the last tile
+		if height-y < opts.Height {
+			err = viewport(opts, height-y).Do(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// scroll to tile and await paint
+		err = chromedp.Evaluate(fmt.Sprintf(`window.scrollTo(0, %d)`, y), nil).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = chromedp.Evaluate(awaitFrame, nil, func(params *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return params.WithAwaitPromise(true)
+		}).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// capture tile
+		buf, err := page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// write tile
+		file := filepath.Join(dir, strconv.Itoa(len(files))+".png")
+		err = os.WriteFile(file, buf, 0644)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	// restore viewport
+	err = viewport(opts, opts.Height).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// join tiles
+	var buf bytes.Buffer
+	err = vips.Join(ctx, files, opts.Format.suffix(opts.Quality), &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
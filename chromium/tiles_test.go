@@ -0,0 +1,72 @@
+package chromium
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	assert.True(t, Format("").Valid())
+	assert.True(t, JPEG.Valid())
+	assert.False(t, Format("gif").Valid())
+
+	assert.Equal(t, ".png", Format("").suffix(80))
+	assert.Equal(t, ".png", PNG.suffix(80))
+	assert.Equal(t, ".jpg", JPEG.suffix(0))
+	assert.Equal(t, ".jpg[Q=80]", JPEG.suffix(80))
+	assert.Equal(t, ".webp[Q=60]", WebP.suffix(60))
+
+	assert.False(t, tiled(ScreenshotOptions{}, 8000))
+	assert.True(t, tiled(ScreenshotOptions{}, 20000))
+	assert.True(t, tiled(ScreenshotOptions{Scale: 2}, 9000))
+	assert.True(t, tiled(ScreenshotOptions{Tiled: true}, 100))
+
+	assert.Equal(t, int64(131072), maxHeight(ScreenshotOptions{}))
+	assert.Equal(t, int64(65536), maxHeight(ScreenshotOptions{Scale: 2}))
+	assert.Equal(t, int64(65535), maxHeight(ScreenshotOptions{Format: JPEG}))
+	assert.Equal(t, int64(8191), maxHeight(ScreenshotOptions{Format: WebP, Scale: 2}))
+}
+
+func TestScreenshotFormat(t *testing.T) {
+	html := `<html><body style="margin: 0"><div style="height: 2500px; background: red"></div></body></html>`
+
+	buf, err := RenderHTML(nil, html, nil, RenderOptions{
+		Screenshot: ScreenshotOptions{
+			Width:   800,
+			Height:  600,
+			Format:  JPEG,
+			Quality: 50,
+		},
+	})
+	assert.NoError(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 800, 600), img.Bounds())
+
+	buf, err = RenderHTML(nil, html, nil, RenderOptions{
+		Screenshot: ScreenshotOptions{
+			Width:  800,
+			Height: 600,
+			Full:   true,
+			Tiled:  true,
+		},
+	})
+	assert.NoError(t, err)
+
+	img, err = png.Decode(bytes.NewReader(buf))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 800, 2500), img.Bounds())
+
+	_, err = Screenshot(nil, "https://example.org", ScreenshotOptions{
+		Format: "gif",
+	})
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "invalid format"))
+}
@@ -0,0 +1,112 @@
+package chromium
+
+import (
+	"context"
+
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// Viewport defines an emulated viewport.
+type Viewport struct {
+	Name   string
+	Width  int64
+	Height int64
+	Scale  float64
+	Mobile bool
+
+	// The user agent used if the options do not set one.
+	UserAgent string
+}
+
+// The user agents of the mobile viewport presets.
+const (
+	MobileUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	TabletUserAgent = "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+)
+
+// The available viewport presets.
+var (
+	MobileViewport  = Viewport{Name: "mobile", Width: 390, Height: 844, Scale: 3, Mobile: true, UserAgent: MobileUserAgent}
+	TabletViewport  = Viewport{Name: "tablet", Width: 820, Height: 1180, Scale: 2, Mobile: true, UserAgent: TabletUserAgent}
+	DesktopViewport = Viewport{Name: "desktop", Width: 1920, Height: 1080, Scale: 1}
+)
+
+// DefaultViewports are the viewports captured by ScreenshotSet if none are
+// specified.
+var DefaultViewports = []Viewport{MobileViewport, TabletViewport, DesktopViewport}
+
+func (v Viewport) apply(opts ScreenshotOptions) ScreenshotOptions {
+	// set viewport
+	opts.Width = v.Width
+	opts.Height = v.Height
+	opts.Scale = v.Scale
+	opts.Mobile = v.Mobile
+
+	// set user agent
+	if opts.UserAgent == "" {
+		opts.UserAgent = v.UserAgent
+	}
+
+	return opts
+}
+
+// ScreenshotSet will load the given URL once and capture a screenshot for each
+// of the configured viewports, defaulting to DefaultViewports. The page is
+// loaded using the first viewport and laid out again for every following
+// viewport, after which the wait conditions are awaited again. Changed user
+// agents only apply to scripts and requests made after the layout, the page is
+// not reloaded. A browser context may be provided using Allocate, otherwise a new
+// one will be allocated.
+func ScreenshotSet(ctx context.Context, url string, opts ScreenshotOptions) ([][]byte, error) {
+	// open tab
+	ctx, cancel, err := openTab(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	// ensure viewports
+	if len(opts.Viewports) == 0 {
+		opts.Viewports = DefaultViewports
+	}
+
+	// capture screenshots
+	res, err := capture(ctx, url, opts, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Images, nil
+}
+
+func relayout(opts ScreenshotOptions) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// emulate viewport
+		err := viewport(opts, opts.Height).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// override user agent, resetting it if not set
+		err = userAgent(opts).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// await layout and paint
+		err = chromedp.Evaluate(awaitFrame, nil, func(params *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return params.WithAwaitPromise(true)
+		}).Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		// scroll through page again to load lazy content
+		if opts.Full {
+			return scroll().Do(ctx)
+		}
+
+		return nil
+	})
+}
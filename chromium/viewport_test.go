@@ -0,0 +1,113 @@
+package chromium
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const responsivePage = `<!DOCTYPE html>
+<html>
+<head>
+	<style>
+		body { margin: 0; background: red; }
+		@media (min-width: 800px) { body { background: green; } }
+	</style>
+</head>
+<body>Hello</body>
+</html>`
+
+func TestScreenshotSet(t *testing.T) {
+	var requests int64
+	var userAgent atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			atomic.AddInt64(&requests, 1)
+			userAgent.Store(r.UserAgent())
+		}
+		_, _ = w.Write([]byte(responsivePage))
+	}))
+	defer server.Close()
+
+	images, err := ScreenshotSet(nil, server.URL, ScreenshotOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, images, 3)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&requests))
+	assert.Equal(t, MobileUserAgent, userAgent.Load())
+
+	for i, vp := range DefaultViewports {
+		img, err := png.Decode(bytes.NewReader(images[i]))
+		assert.NoError(t, err)
+		assert.Equal(t, int(float64(vp.Width)*vp.Scale), img.Bounds().Dx())
+		assert.Equal(t, int(float64(vp.Height)*vp.Scale), img.Bounds().Dy())
+
+		r, g, _, _ := img.At(0, 0).RGBA()
+		if vp.Width < 800 {
+			assert.True(t, r > g, vp.Name)
+		} else {
+			assert.True(t, g > r, vp.Name)
+		}
+	}
+
+	res, err := Capture(nil, server.URL, ScreenshotOptions{
+		Viewports: []Viewport{
+			{Name: "small", Width: 320, Height: 240, Scale: 1},
+			{Name: "large", Width: 1024, Height: 768, Scale: 1},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Image)
+	assert.Len(t, res.Images, 2)
+}
+
+const relayoutPage = `<!DOCTYPE html>
+<html>
+<head>
+	<style>
+		body { margin: 0; background: red; }
+		body[data-agent="large"] { background: green; }
+	</style>
+	<script>
+		const update = () => setTimeout(() => document.body.dataset.agent = navigator.userAgent, 200);
+		window.addEventListener("load", update);
+		window.addEventListener("resize", update);
+	</script>
+</head>
+<body>Hello</body>
+</html>`
+
+func TestScreenshotSetRelayout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(relayoutPage))
+	}))
+	defer server.Close()
+
+	images, err := ScreenshotSet(nil, server.URL, ScreenshotOptions{
+		Viewports: []Viewport{
+			{Name: "small", Width: 320, Height: 240, Scale: 1, UserAgent: "small"},
+			{Name: "large", Width: 1024, Height: 768, Scale: 1, UserAgent: "large"},
+		},
+		WaitFor: []Condition{
+			Expression(`document.body.dataset.agent === navigator.userAgent`),
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, images, 2)
+
+	for i, name := range []string{"small", "large"} {
+		img, err := png.Decode(bytes.NewReader(images[i]))
+		assert.NoError(t, err)
+
+		r, g, _, _ := img.At(0, 0).RGBA()
+		if name == "small" {
+			assert.True(t, r > g, name)
+		} else {
+			assert.True(t, g > r, name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package chromium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/256dpi/xo"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultWaitTimeout is the default deadline for all wait conditions.
+const DefaultWaitTimeout = 30 * time.Second
+
+const pollInterval = 50 * time.Millisecond
+
+// Condition is a condition awaited before capturing.
+type Condition struct {
+	name string
+	fn   func(ctx context.Context, t *tracker) error
+}
+
+// NetworkIdle returns a condition that is met once no requests have been in
+// flight for the specified duration.
+func NetworkIdle(idle time.Duration) Condition {
+	return Condition{
+		name: "network idle",
+		fn: func(ctx context.Context, t *tracker) error {
+			return poll(ctx, func() (bool, error) {
+				return t.idle(idle, time.Now()), nil
+			})
+		},
+	}
+}
+
+// SelectorPresent returns a condition that is met once an element matches
+// the specified CSS selector.
+func SelectorPresent(selector string) Condition {
+	cond := Expression(fmt.Sprintf(`document.querySelector(%s) !== null`, jsString(selector)))
+	cond.name = fmt.Sprintf("selector %q", selector)
+	return cond
+}
+
+// SelectorAbsent returns a condition that is met once no element matches the
+// specified CSS selector.
+func SelectorAbsent(selector string) Condition {
+	cond := Expression(fmt.Sprintf(`document.querySelector(%s) === null`, jsString(selector)))
+	cond.name = fmt.Sprintf("absence of selector %q", selector)
+	return cond
+}
+
+// Expression returns a condition that is met once the specified JavaScript
+// expression evaluates to a truthy value.
+func Expression(expr string) Condition {
+	return Condition{
+		name: fmt.Sprintf("expression %q", expr),
+		fn: func(ctx context.Context, _ *tracker) error {
+			return poll(ctx, func() (bool, error) {
+				var ok bool
+				err := chromedp.Evaluate(fmt.Sprintf(`!!(%s)`, expr), &ok).Do(ctx)
+				return ok, err
+			})
+		},
+	}
+}
+
+// FontsReady returns a condition that is met once all fonts have been loaded.
+func FontsReady() Condition {
+	return Condition{
+		name: "fonts",
+		fn: func(ctx context.Context, _ *tracker) error {
+			return chromedp.Evaluate(`document.fonts.ready.then(() => true)`, nil, func(params *runtime.EvaluateParams) *runtime.EvaluateParams {
+				return params.WithAwaitPromise(true)
+			}).Do(ctx)
+		},
+	}
+}
+
+// ImagesLoaded returns a condition that is met once all images have been
+// loaded.
+func ImagesLoaded() Condition {
+	cond := Expression(`Array.from(document.images).every((img) => img.complete)`)
+	cond.name = "images"
+	return cond
+}
+
+func wait(conditions []Condition, timeout time.Duration, t *tracker) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		// get timeout
+		if timeout <= 0 {
+			timeout = DefaultWaitTimeout
+		}
+
+		// apply deadline
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		// await conditions
+		for _, cond := range conditions {
+			err := cond.fn(ctx, t)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return xo.F("waiting for %s timed out", cond.name)
+			} else if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func poll(ctx context.Context, check func() (bool, error)) error {
+	for {
+		// check condition
+		ok, err := check()
+		if err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+
+		// await next check
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// tracker tracks in-flight requests of a tab.
+type tracker struct {
+	mutex    sync.Mutex
+	inflight map[network.RequestID]bool
+	last     time.Time
+}
+
+func track(ctx context.Context) *tracker {
+	// prepare tracker
+	t := &tracker{
+		inflight: map[network.RequestID]bool{},
+		last:     time.Now(),
+	}
+
+	// track requests
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			t.update(ev.RequestID, true)
+		case *network.EventLoadingFinished:
+			t.update(ev.RequestID, false)
+		case *network.EventLoadingFailed:
+			t.update(ev.RequestID, false)
+		}
+	})
+
+	return t
+}
+
+func (t *tracker) update(id network.RequestID, started bool) {
+	// acquire mutex
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// update requests
+	if started {
+		t.inflight[id] = true
+	} else {
+		delete(t.inflight, id)
+	}
+	t.last = time.Now()
+}
+
+func (t *tracker) idle(idle time.Duration, now time.Time) bool {
+	// acquire mutex
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return len(t.inflight) == 0 && now.Sub(t.last) >= idle
+}
@@ -0,0 +1,70 @@
+package chromium
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitConditions(t *testing.T) {
+	html := `<html><body>
+		<img src="missing.png">
+		<div id="loading">Loading</div>
+		<script>
+			setTimeout(() => {
+				document.getElementById('loading').remove();
+				const el = document.createElement('div');
+				el.id = 'ready';
+				document.body.appendChild(el);
+				window.done = true;
+			}, 200);
+		</script>
+	</body></html>`
+
+	buf, err := RenderHTML(nil, html, nil, RenderOptions{
+		Screenshot: ScreenshotOptions{
+			WaitFor: []Condition{
+				NetworkIdle(100 * time.Millisecond),
+				SelectorPresent("#ready"),
+				SelectorAbsent("#loading"),
+				Expression("window.done"),
+				FontsReady(),
+				ImagesLoaded(),
+			},
+			WaitTimeout: 5 * time.Second,
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, buf)
+
+	_, err = RenderHTML(nil, html, nil, RenderOptions{
+		Screenshot: ScreenshotOptions{
+			WaitFor: []Condition{
+				SelectorPresent("#never"),
+			},
+			WaitTimeout: 500 * time.Millisecond,
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, `waiting for selector "#never" timed out`, err.Error())
+}
+
+func TestTrackerIdle(t *testing.T) {
+	tr := &tracker{
+		inflight: map[network.RequestID]bool{},
+		last:     time.Now(),
+	}
+
+	now := time.Now()
+	assert.False(t, tr.idle(time.Second, now))
+	assert.True(t, tr.idle(time.Second, now.Add(time.Second)))
+
+	tr.update("1", true)
+	assert.False(t, tr.idle(time.Second, now.Add(2*time.Second)))
+
+	tr.update("1", false)
+	assert.False(t, tr.idle(time.Second, time.Now()))
+	assert.True(t, tr.idle(time.Second, time.Now().Add(time.Second)))
+}
@@ -8,14 +8,23 @@ import (
 	"io"
 	"math"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/cache"
+	"github.com/256dpi/mediakit/run"
 )
 
+// Runner is the runner used to execute the ffmpeg and ffprobe utilities.
+var Runner run.Runner = run.Default
+
+// Cache is an optional store used to cache analysis reports of seekable inputs
+// by their content hash.
+var Cache cache.Store
+
 var imageCodecs = []string{
 	"png",
 	"mjpeg",
@@ -24,6 +33,11 @@ var imageCodecs = []string{
 	"webp",
 }
 
+var animationCodecs = []string{
+	"gif",
+	"apng",
+}
+
 // Format is a ffprobe format.
 type Format struct {
 	Name     string  `json:"format_name"`
@@ -94,6 +108,7 @@ type Report struct {
 	Duration float64
 	Format   Format   `json:"format"`
 	Streams  []Stream `json:"streams"`
+	Frames   int
 	DidScan  bool
 }
 
@@ -153,16 +168,55 @@ func (r Report) SampleRate() int {
 // return the parsed report. If the input is an *os.File and has a name it will
 // be mapped via the filesystem. Otherwise, a pipe is created to connect the
 // input. Using a file is recommended to allow ffprobe to seek within the file.
+// If a cache is configured, reports of seekable inputs are cached.
 func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	// ensure context
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	// analyze input if cache is absent
+	if Cache == nil {
+		return analyze(ctx, r)
+	}
+
+	// check cache
+	var report Report
+	key, ok, err := cache.Lookup(Cache, "ffmpeg:", r, &report)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		return &report, nil
+	}
+
+	// analyze input
+	rep, err := analyze(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// cache report
+	if key != "" {
+		err = cache.Save(Cache, key, rep)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rep, nil
+}
+
+func analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	// check input
 	file, _ := r.(*os.File)
 	isFile := file != nil && file.Name() != ""
 
+	// check demuxer
+	input, err := checkInput(r)
+	if err != nil {
+		return nil, err
+	}
+
 	// prepare args
 	args := []string{
 		"-print_format", "json",
@@ -171,6 +225,9 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 		"-show_error",
 	}
 
+	// restrict protocols
+	args = append(args, protocolArgs(isFile)...)
+
 	// add input
 	if isFile {
 		args = append(args, file.Name())
@@ -179,11 +236,14 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	}
 
 	// prepare command
-	cmd := exec.CommandContext(ctx, "ffprobe", args...)
+	cmd := run.Command{
+		Name: "ffprobe",
+		Args: args,
+	}
 
 	// set input
 	if !isFile {
-		cmd.Stdin = r
+		cmd.Stdin = input
 	}
 
 	// set outputs
@@ -192,8 +252,13 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	cmd.Stderr = &stderr
 
 	// run command
-	err := cmd.Run()
+	err = Runner.Run(ctx, cmd)
 	if err != nil {
+		// check protocol
+		if err := checkProtocol(stderr.String()); err != nil {
+			return nil, err
+		}
+
 		// decode report
 		var report struct {
 			Error struct {
@@ -215,6 +280,12 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 		return nil, err
 	}
 
+	// check demuxer
+	err = checkDemuxer(report.Format.Name)
+	if err != nil {
+		return nil, err
+	}
+
 	// find duration
 	report.Duration = report.Format.Duration
 	for _, stream := range report.Streams {
@@ -238,6 +309,14 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	// get seeker
 	seeker, _ := r.(io.Seeker)
 
+	// count frames of animations as their duration may be zero
+	if len(report.Streams) == 1 && lo.Contains(animationCodecs, report.Streams[0].Codec) && (isFile || seeker != nil) {
+		report.Frames, err = countFrames(ctx, r, isFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// decode full file to get duration if still missing
 	if !image && report.Duration == 0 && seeker != nil {
 		// set flag
@@ -249,14 +328,23 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 			return nil, err
 		}
 
-		// prepare command
-		cmd = exec.CommandContext(ctx, "ffmpeg", "-nostats", "-hide_banner", "-i", "pipe:", "-f", "null", "-")
+		// prepare args
+		args = []string{"-nostats", "-hide_banner"}
+		args = append(args, protocolArgs(false)...)
+		args = append(args, "-i", "pipe:", "-f", "null", "-")
 
-		// set input
-		cmd.Stdin = r
+		// prepare command
+		var output bytes.Buffer
+		cmd = run.Command{
+			Name:   "ffmpeg",
+			Args:   args,
+			Stdin:  r,
+			Stdout: &output,
+			Stderr: &output,
+		}
 
 		// run command
-		out, err := cmd.CombinedOutput()
+		err = Runner.Run(ctx, cmd)
 		if err != nil {
 			if stderr.Len() > 0 {
 				return nil, fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
@@ -266,7 +354,7 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 
 		// find duration string
 		var durStr string
-		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
 		for i := len(lines) - 1; i >= 0; i-- {
 			if strings.Contains(lines[i], " time=") {
 				parts := strings.Split(lines[i], " ")
@@ -295,6 +383,61 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	return &report, nil
 }
 
+func countFrames(ctx context.Context, r io.Reader, isFile bool) (int, error) {
+	// prepare args
+	args := []string{
+		"-print_format", "json",
+		"-count_packets",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=nb_read_packets",
+	}
+	args = append(args, protocolArgs(isFile)...)
+
+	// prepare command
+	var stdout, stderr bytes.Buffer
+	cmd := run.Command{
+		Name:   "ffprobe",
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+
+	// set input
+	if isFile {
+		cmd.Args = append(args, r.(*os.File).Name())
+	} else {
+		_, err := r.(io.Seeker).Seek(0, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		cmd.Args = append(args, "pipe:")
+		cmd.Stdin = r
+	}
+
+	// run command
+	err := Runner.Run(ctx, cmd)
+	if err != nil {
+		if stderr.Len() > 0 {
+			return 0, fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
+		}
+		return 0, fmt.Errorf("ffprobe: %s", err.Error())
+	}
+
+	// decode report
+	var report struct {
+		Streams []struct {
+			Packets int `json:"nb_read_packets,string"`
+		} `json:"streams"`
+	}
+	err = json.Unmarshal(stdout.Bytes(), &report)
+	if err != nil {
+		return 0, err
+	} else if len(report.Streams) == 0 {
+		return 0, nil
+	}
+
+	return report.Streams[0].Packets, nil
+}
+
 func parseDuration(str string) (time.Duration, error) {
 	// parse string
 	ts, err := time.Parse("15:04:05.999999999", str)
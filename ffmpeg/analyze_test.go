@@ -2,6 +2,7 @@ package ffmpeg
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"strings"
 	"testing"
@@ -9,6 +10,8 @@ import (
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/256dpi/mediakit/cache"
+	"github.com/256dpi/mediakit/run"
 	"github.com/256dpi/mediakit/samples"
 )
 
@@ -277,8 +280,10 @@ func TestAnalyzeAnimation(t *testing.T) {
 			}
 
 			frameRate := 25
+			var frames int
 			if item.sample == samples.AnimationGIF {
 				frameRate = 5
+				frames = 10
 			}
 
 			assert.Equal(t, &Report{
@@ -299,6 +304,7 @@ func TestAnalyzeAnimation(t *testing.T) {
 						ColorSpace:  item.colSpc,
 					},
 				},
+				Frames: frames,
 			}, report)
 		})
 	}
@@ -311,12 +317,14 @@ func TestAnalyzeImage(t *testing.T) {
 		codec  string
 		pixFmt string
 		colSpc string
+		frames int
 	}{
 		{
 			sample: samples.ImageGIF,
 			format: "gif",
 			codec:  "gif",
 			pixFmt: "bgra",
+			frames: 1,
 		},
 		{
 			sample: samples.ImageJPEG,
@@ -376,6 +384,7 @@ func TestAnalyzeImage(t *testing.T) {
 						ColorSpace:  item.colSpc,
 					},
 				},
+				Frames: item.frames,
 			}, report)
 		})
 	}
@@ -417,6 +426,52 @@ func TestAnalyzeError(t *testing.T) {
 	assert.Equal(t, "invalid data found when processing input", err.Error())
 }
 
+func TestAnalyzeCache(t *testing.T) {
+	var runs int
+	Runner = run.Func(func(ctx context.Context, cmd run.Command) error {
+		runs++
+		_, err := io.WriteString(cmd.Stdout, `{
+			"streams": [{"codec_type": "audio", "codec_name": "mp3", "duration": "2.1", "channels": 2, "sample_rate": "44100"}],
+			"format": {"format_name": "mp3", "duration": "2.1"}
+		}`)
+		return err
+	})
+	Cache = cache.NewMemory(10)
+	defer func() {
+		Runner = run.Default
+		Cache = nil
+	}()
+
+	report1, err := Analyze(nil, strings.NewReader("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+
+	report2, err := Analyze(nil, strings.NewReader("foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, report1, report2)
+	assert.Equal(t, &Report{
+		Duration: 2.1,
+		Format: Format{
+			Name:     "mp3",
+			Duration: 2.1,
+		},
+		Streams: []Stream{
+			{
+				Type:       "audio",
+				Codec:      "mp3",
+				Duration:   2.1,
+				Channels:   2,
+				SampleRate: 44100,
+			},
+		},
+	}, report2)
+
+	_, err = Analyze(nil, strings.NewReader("bar"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, runs)
+}
+
 func BenchmarkAnalyze(b *testing.B) {
 	sample := samples.Load(samples.AudioMPEG3)
 	defer sample.Close()
@@ -0,0 +1,169 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+// Features describes the installed ffmpeg and ffprobe utilities.
+type Features struct {
+	FFmpegVersion  string
+	FFprobeVersion string
+	Encoders       []string
+	Decoders       []string
+	Muxers         []string
+	Demuxers       []string
+	Filters        []string
+}
+
+// Capabilities will run the ffmpeg and ffprobe utilities to discover their
+// versions and the available encoders, decoders, muxers, demuxers and filters.
+func Capabilities(ctx context.Context) (*Features, error) {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// get versions
+	ffmpegVersion, err := query(ctx, "ffmpeg", "-version")
+	if err != nil {
+		return nil, err
+	}
+	ffprobeVersion, err := query(ctx, "ffprobe", "-version")
+	if err != nil {
+		return nil, err
+	}
+
+	// get lists
+	var lists [5]string
+	for i, arg := range []string{"-encoders", "-decoders", "-muxers", "-demuxers", "-filters"} {
+		lists[i], err = query(ctx, "ffmpeg", arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Features{
+		FFmpegVersion:  parseVersion(ffmpegVersion),
+		FFprobeVersion: parseVersion(ffprobeVersion),
+		Encoders:       parseList(lists[0]),
+		Decoders:       parseList(lists[1]),
+		Muxers:         parseList(lists[2]),
+		Demuxers:       parseList(lists[3]),
+		Filters:        parseFilters(lists[4]),
+	}, nil
+}
+
+// Supports returns an error if the specified preset cannot be used with the
+// available muxers, encoders and filters.
+func (f *Features) Supports(p Preset) error {
+	// check preset
+	if !p.Valid() {
+		return fmt.Errorf("invalid preset")
+	}
+
+	// check args
+	var missing []string
+	args := p.Args(true)
+	for i := 0; i < len(args)-1; i++ {
+		switch args[i] {
+		case "-f":
+			if !lo.Contains(f.Muxers, args[i+1]) {
+				missing = append(missing, "muxer "+args[i+1])
+			}
+		case "-codec:v", "-codec:a":
+			if !lo.Contains(f.Encoders, args[i+1]) {
+				missing = append(missing, "encoder "+args[i+1])
+			}
+		}
+	}
+
+	// collect filters
+	filters := []string{"scale"}
+	for _, filter := range p.Filters() {
+		filters = append(filters, strings.Split(filter, "=")[0])
+	}
+	if p == AnimationGIF {
+		filters = append(filters, "palettegen", "paletteuse")
+	}
+
+	// check filters
+	for _, filter := range lo.Uniq(filters) {
+		if !lo.Contains(f.Filters, filter) {
+			missing = append(missing, "filter "+filter)
+		}
+	}
+
+	// check missing
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func query(ctx context.Context, name, arg string) (string, error) {
+	// run command
+	var stdout, stderr bytes.Buffer
+	err := Runner.Run(ctx, run.Command{
+		Name:   name,
+		Args:   []string{"-hide_banner", arg},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
+		}
+		return "", fmt.Errorf("%s: %s", name, err.Error())
+	}
+
+	return stdout.String(), nil
+}
+
+func parseVersion(out string) string {
+	// parse "ffmpeg version 6.1.1 Copyright ..."
+	fields := strings.Fields(strings.SplitN(out, "\n", 2)[0])
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+
+	return ""
+}
+
+func parseList(out string) []string {
+	// collect names after the separator line
+	var list []string
+	var started bool
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if !started {
+			started = len(fields) == 1 && strings.Trim(fields[0], "-") == ""
+			continue
+		}
+		if len(fields) >= 2 {
+			list = append(list, strings.Split(fields[1], ",")...)
+		}
+	}
+
+	return list
+}
+
+func parseFilters(out string) []string {
+	// collect names of lines with an "A->V" style description
+	var list []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			list = append(list, fields[1])
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,99 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+const versionOutput = `ffmpeg version 6.1.1 Copyright (c) 2000-2023 the FFmpeg developers
+built with gcc 13.2.1 (Alpine 13.2.1_git20231014) 20231014
+`
+
+const encodersOutput = `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D gif                  GIF (Graphics Interchange Format)
+ V....D libwebp              libwebp WebP image (codec webp)
+ A....D libmp3lame           libmp3lame MP3 (MPEG audio layer 3) (codec mp3)
+`
+
+const muxersOutput = `Muxers:
+ D. = Demuxing supported
+ .E = Muxing supported
+ ---
+  E gif             CompuServe Graphics Interchange Format (GIF)
+  E image2          image2 sequence
+  E mp3             MP3 (MPEG audio layer 3)
+`
+
+const demuxersOutput = `Demuxers:
+ D. = Demuxing supported
+ .E = Muxing supported
+ ---
+ D  aac             raw ADTS AAC (Advanced Audio Coding)
+ D  mov,mp4,m4a,3gp,3g2,mj2 QuickTime / MOV
+`
+
+const filtersOutput = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  A = Audio input/output
+  V = Video input/output
+  | = Source or sink filter
+ ..C palettegen        V->V       Find the optimal palette for a given stream.
+ ... paletteuse        VV->V      Use a palette to downsample an input video stream.
+ ..C scale             V->V       Scale the input video size and/or convert the image format.
+`
+
+func fakeRunner() run.Runner {
+	return run.Func(func(ctx context.Context, cmd run.Command) error {
+		var out string
+		switch cmd.Args[len(cmd.Args)-1] {
+		case "-version":
+			out = versionOutput
+		case "-encoders", "-decoders":
+			out = encodersOutput
+		case "-muxers":
+			out = muxersOutput
+		case "-demuxers":
+			out = demuxersOutput
+		case "-filters":
+			out = filtersOutput
+		}
+		_, err := io.WriteString(cmd.Stdout, out)
+		return err
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	Runner = fakeRunner()
+	defer func() {
+		Runner = run.Default
+	}()
+
+	features, err := Capabilities(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &Features{
+		FFmpegVersion:  "6.1.1",
+		FFprobeVersion: "6.1.1",
+		Encoders:       []string{"gif", "libwebp", "libmp3lame"},
+		Decoders:       []string{"gif", "libwebp", "libmp3lame"},
+		Muxers:         []string{"gif", "image2", "mp3"},
+		Demuxers:       []string{"aac", "mov", "mp4", "m4a", "3gp", "3g2", "mj2"},
+		Filters:        []string{"palettegen", "paletteuse", "scale"},
+	}, features)
+
+	assert.NoError(t, features.Supports(AudioMP3VBRStandard))
+	assert.NoError(t, features.Supports(AnimationGIF))
+	assert.NoError(t, features.Supports(ImageWebP))
+
+	err = features.Supports(VideoMP4H264AACFast)
+	assert.Error(t, err)
+	assert.Equal(t, "missing muxer mp4, encoder libx264, encoder aac, filter pad, filter format", err.Error())
+}
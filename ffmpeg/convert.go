@@ -8,10 +8,11 @@ import (
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/256dpi/mediakit/run"
 )
 
 // WarningsLogger is the logger used to print warnings.
@@ -53,6 +54,39 @@ func (p Preset) Valid() bool {
 	return len(p.Args(false)) != 0
 }
 
+// String returns the name of the preset.
+func (p Preset) String() string {
+	switch p {
+	case AudioMP3VBRStandard:
+		return "AudioMP3VBRStandard"
+	case VideoMP4H264AACFast:
+		return "VideoMP4H264AACFast"
+	case ImageJPEG:
+		return "ImageJPEG"
+	case ImagePNG:
+		return "ImagePNG"
+	case ImageWebP:
+		return "ImageWebP"
+	case AnimationGIF:
+		return "AnimationGIF"
+	case AnimationWebP:
+		return "AnimationWebP"
+	default:
+		return "Preset(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// ParsePreset returns the preset with the specified name. The name is matched
+// case-insensitively. Zero is returned if no preset matches.
+func ParsePreset(name string) Preset {
+	for p := Preset(1); p.Valid(); p++ {
+		if strings.EqualFold(p.String(), name) {
+			return p
+		}
+	}
+	return 0
+}
+
 // Args returns the ffmpeg args for the preset.
 func (p Preset) Args(isFile bool) []string {
 	switch p {
@@ -182,9 +216,23 @@ type ConvertOptions struct {
 	// Force a sample rate.
 	SampleRate int
 
+	// Force the input format and frame rate for inputs that are not
+	// self-describing like image sequences. Only "image2pipe" is supported.
+	InputFormat    string
+	InputFrameRate float64
+
+	// Reject inputs with larger frames.
+	MaxPixels int
+
+	// Reject inputs with more streams.
+	MaxStreams int
+
 	// Receive progress updates.
 	ProgressFunc func(Progress)
 	ProgressRate time.Duration
+
+	// Limit the resources used by the ffmpeg processes.
+	Limits run.Limits
 }
 
 // Convert will run the ffmpeg utility to convert the specified input to the
@@ -209,6 +257,40 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 		return fmt.Errorf("invalid preset")
 	}
 
+	// check input format
+	if opts.InputFormat != "" && opts.InputFormat != "image2pipe" {
+		return fmt.Errorf("unsupported input format: %s", opts.InputFormat)
+	}
+
+	// check demuxer
+	r, err := checkInput(r)
+	if err != nil {
+		return err
+	}
+
+	// probe demuxer of files, pipes cannot reference files
+	if rIsFile {
+		err = probeInput(ctx, rFile.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	// prepare input args
+	inputArgs := protocolArgs(rIsFile)
+	if opts.MaxPixels > 0 {
+		inputArgs = append(inputArgs, "-max_pixels", strconv.Itoa(opts.MaxPixels))
+	}
+	if opts.MaxStreams > 0 {
+		inputArgs = append(inputArgs, "-max_streams", strconv.Itoa(opts.MaxStreams))
+	}
+	if opts.InputFormat != "" {
+		inputArgs = append(inputArgs, "-f", opts.InputFormat)
+	}
+	if opts.InputFrameRate != 0 {
+		inputArgs = append(inputArgs, "-framerate", strconv.FormatFloat(opts.InputFrameRate, 'f', -1, 64))
+	}
+
 	// generate palette for GIF images
 	var palette *os.File
 	if opts.Preset == AnimationGIF {
@@ -217,15 +299,25 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 			return fmt.Errorf("GIF requires file input")
 		}
 
+		// prepare args
+		args := []string{"-nostats", "-hide_banner", "-loglevel", "repeat+warning", "-y"}
+		args = append(args, inputArgs...)
+		args = append(args, "-i", rFile.Name(), "-vf", "palettegen", "-f", "image2pipe", "-vcodec", "png", "pipe:")
+
 		// prepare command
-		cmd := exec.CommandContext(ctx, "ffmpeg", []string{
-			"-nostats", "-hide_banner", "-loglevel", "repeat+warning", "-y", "-i", rFile.Name(),
-			"-vf", "palettegen", "-f", "image2pipe", "-vcodec", "png", "pipe:",
-		}...)
+		var out bytes.Buffer
+		cmd := run.Command{
+			Name:   "ffmpeg",
+			Args:   args,
+			Stdout: &out,
+			Limits: opts.Limits,
+		}
 
 		// run command
-		out, err := cmd.Output()
-		if err != nil {
+		err := Runner.Run(ctx, cmd)
+		if run.IsLimit(err) {
+			return fmt.Errorf("ffmpeg: %w", err)
+		} else if err != nil {
 			return fmt.Errorf("ffmpeg: %s", err.Error())
 		}
 
@@ -237,7 +329,7 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 		}
 		go func() {
 			defer pw.Close()
-			_, _ = pw.Write(out)
+			_, _ = pw.Write(out.Bytes())
 		}()
 	}
 
@@ -255,6 +347,7 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 	}
 
 	// add input(s)
+	args = append(args, inputArgs...)
 	if rIsFile {
 		args = append(args, "-i", rFile.Name())
 	} else {
@@ -309,6 +402,14 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 		args = append(args, "-ar", strconv.Itoa(opts.SampleRate))
 	}
 
+	// handle limits
+	if opts.Limits.Threads > 0 {
+		args = append(args, "-threads", strconv.Itoa(opts.Limits.Threads))
+	}
+	if opts.Limits.Output > 0 && wIsFile {
+		args = append(args, "-fs", strconv.FormatInt(opts.Limits.Output, 10))
+	}
+
 	// finish args
 	if wIsFile {
 		args = append(args, wFile.Name())
@@ -317,7 +418,11 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 	}
 
 	// prepare command
-	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd := run.Command{
+		Name:   "ffmpeg",
+		Args:   args,
+		Limits: opts.Limits,
+	}
 
 	// set input
 	if !rIsFile {
@@ -374,14 +479,29 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 	}
 
 	// run command
-	err := cmd.Run()
-	if err != nil {
+	err = Runner.Run(ctx, cmd)
+	if run.IsLimit(err) {
+		return fmt.Errorf("ffmpeg: %w", err)
+	} else if err != nil {
+		if err := checkProtocol(stderr.String()); err != nil {
+			return err
+		}
 		if stderr.Len() > 0 {
 			return fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
 		}
 		return fmt.Errorf("ffmpeg: %s", err.Error())
 	}
 
+	// check output size, ffmpeg stops writing silently at the limit
+	if opts.Limits.Output > 0 && wIsFile {
+		info, err := wFile.Stat()
+		if err != nil {
+			return err
+		} else if info.Size() >= opts.Limits.Output {
+			return fmt.Errorf("ffmpeg: %w", run.ErrOutputLimit)
+		}
+	}
+
 	// print warnings
 	if WarningsLogger != nil {
 		scanner := bufio.NewScanner(&stderr)
@@ -2,6 +2,7 @@ package ffmpeg
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"strconv"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/256dpi/mediakit/run"
 	"github.com/256dpi/mediakit/samples"
 )
 
@@ -468,3 +470,98 @@ func TestConvertError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid data found when processing input")
 }
+
+func TestConvertRunner(t *testing.T) {
+	recorder := &run.Recorder{}
+	Runner = recorder
+	defer func() {
+		Runner = run.Default
+	}()
+
+	err := Convert(nil, strings.NewReader("foo"), io.Discard, ConvertOptions{
+		Preset:   AudioMP3VBRStandard,
+		Duration: 1,
+	})
+	assert.NoError(t, err)
+
+	commands := recorder.Commands()
+	assert.Len(t, commands, 1)
+	assert.Equal(t, "ffmpeg", commands[0].Name)
+	assert.Equal(t, []string{
+		"-nostats", "-hide_banner", "-loglevel", "repeat+warning", "-y",
+		"-protocol_whitelist", "pipe", "-i", "pipe:",
+		"-f", "mp3", "-codec:a", "libmp3lame", "-q:a", "2", "-ac", "2",
+		"-t", "1",
+		"pipe:",
+	}, commands[0].Args)
+}
+
+func TestConvertInput(t *testing.T) {
+	recorder := &run.Recorder{}
+	Runner = recorder
+	defer func() {
+		Runner = run.Default
+	}()
+
+	err := Convert(nil, strings.NewReader("frames"), io.Discard, ConvertOptions{
+		Preset:         AnimationWebP,
+		InputFormat:    "image2pipe",
+		InputFrameRate: 24,
+	})
+	assert.NoError(t, err)
+
+	commands := recorder.Commands()
+	assert.Len(t, commands, 1)
+	assert.Equal(t, []string{
+		"-protocol_whitelist", "pipe", "-f", "image2pipe", "-framerate", "24", "-i", "pipe:",
+	}, commands[0].Args[5:13])
+
+	err = Convert(nil, strings.NewReader("ffconcat version 1.0"), io.Discard, ConvertOptions{
+		Preset:      AnimationWebP,
+		InputFormat: "concat",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "unsupported input format: concat", err.Error())
+	assert.Len(t, recorder.Commands(), 1)
+}
+
+func TestConvertLimits(t *testing.T) {
+	recorder := &run.Recorder{}
+	Runner = recorder
+	defer func() {
+		Runner = run.Default
+	}()
+
+	limits := run.Limits{
+		Threads: 2,
+		Timeout: time.Minute,
+	}
+
+	err := Convert(nil, strings.NewReader("foo"), io.Discard, ConvertOptions{
+		Preset: AudioMP3VBRStandard,
+		Limits: limits,
+	})
+	assert.NoError(t, err)
+
+	commands := recorder.Commands()
+	assert.Len(t, commands, 1)
+	assert.Equal(t, limits, commands[0].Limits)
+	assert.Equal(t, []string{"-threads", "2", "pipe:"}, commands[0].Args[len(commands[0].Args)-3:])
+
+	Runner = run.Func(func(context.Context, run.Command) error {
+		return run.ErrTimeLimit
+	})
+
+	err = Convert(nil, strings.NewReader("foo"), io.Discard, ConvertOptions{
+		Preset: AudioMP3VBRStandard,
+		Limits: limits,
+	})
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, run.ErrTimeLimit)
+}
+
+func TestParsePreset(t *testing.T) {
+	assert.Equal(t, AudioMP3VBRStandard, ParsePreset("AudioMP3VBRStandard"))
+	assert.Equal(t, AnimationWebP, ParsePreset("animationwebp"))
+	assert.Equal(t, Preset(0), ParsePreset("foo"))
+}
@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+// AllowedProtocols is the list of protocols ffprobe and ffmpeg may use to
+// open inputs, including resources referenced by an input. The "file"
+// protocol is only allowed for file inputs.
+var AllowedProtocols = []string{"file", "pipe"}
+
+// AllowedDemuxers is the list of otherwise blocked playlist-style demuxers
+// that may be used to read inputs.
+var AllowedDemuxers []string
+
+// BlockedDemuxers is the list of playlist-style demuxers that reference other
+// resources and are blocked unless explicitly allowed using AllowedDemuxers.
+var BlockedDemuxers = []string{
+	"concat",
+	"dash",
+	"hls",
+	"imf",
+	"sdp",
+}
+
+// UnsafeInputError is returned if an input attempts to use a blocked protocol
+// or demuxer.
+type UnsafeInputError struct {
+	Protocol string
+	Demuxer  string
+}
+
+// Error implements the error interface.
+func (e *UnsafeInputError) Error() string {
+	if e.Protocol != "" {
+		return fmt.Sprintf("unsafe input: blocked protocol %q", e.Protocol)
+	}
+	return fmt.Sprintf("unsafe input: blocked demuxer %q", e.Demuxer)
+}
+
+var blockedProtocolPattern = regexp.MustCompile(`Protocol '(\w+)' not on whitelist`)
+
+// inputPeekBytes is the number of bytes inspected to detect demuxers.
+const inputPeekBytes = 1024
+
+func protocolArgs(file bool) []string {
+	// prevent pipe inputs from referencing files
+	protocols := AllowedProtocols
+	if !file {
+		protocols = lo.Without(protocols, "file")
+	}
+
+	return []string{"-protocol_whitelist", strings.Join(protocols, ",")}
+}
+
+func checkInput(r io.Reader) (io.Reader, error) {
+	// read head
+	buf := make([]byte, inputPeekBytes)
+	var n int
+	var err error
+	if file, ok := r.(*os.File); ok && file.Name() != "" {
+		n, err = file.ReadAt(buf, 0)
+	} else {
+		n, err = io.ReadFull(r, buf)
+		r = io.MultiReader(bytes.NewReader(buf[:n]), r)
+	}
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	// check demuxer
+	demuxer := detectDemuxer(buf[:n])
+	if demuxer != "" {
+		err = checkDemuxer(demuxer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func probeInput(ctx context.Context, name string) error {
+	// prepare args
+	args := []string{
+		"-print_format", "json",
+		"-show_format",
+		"-show_error",
+	}
+	args = append(args, protocolArgs(true)...)
+	args = append(args, name)
+
+	// run command
+	var stdout, stderr bytes.Buffer
+	err := Runner.Run(ctx, run.Command{
+		Name:   "ffprobe",
+		Args:   args,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		// check protocol
+		if err := checkProtocol(stderr.String()); err != nil {
+			return err
+		}
+
+		// decode report
+		var report struct {
+			Error struct {
+				String string `json:"string"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(stdout.Bytes(), &report)
+		if report.Error.String != "" {
+			return fmt.Errorf(strings.ToLower(report.Error.String))
+		}
+
+		return fmt.Errorf("ffprobe: %s", err.Error())
+	}
+
+	// decode report
+	var report struct {
+		Format Format `json:"format"`
+	}
+	err = json.Unmarshal(stdout.Bytes(), &report)
+	if err != nil {
+		return err
+	}
+
+	return checkDemuxer(report.Format.Name)
+}
+
+func detectDemuxer(buf []byte) string {
+	// trim byte order mark and whitespace
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	buf = bytes.TrimLeft(buf, " \t\r\n")
+
+	// detect playlist-style formats
+	switch {
+	case bytes.HasPrefix(buf, []byte("#EXTM3U")):
+		return "hls"
+	case bytes.HasPrefix(buf, []byte("ffconcat version")):
+		return "concat"
+	case bytes.HasPrefix(buf, []byte("<")) && bytes.Contains(buf, []byte("<MPD")):
+		return "dash"
+	case bytes.HasPrefix(buf, []byte("v=0")) && bytes.Contains(buf, []byte("\nm=")):
+		return "sdp"
+	}
+
+	return ""
+}
+
+func checkDemuxer(name string) error {
+	// check names, formats may have multiple comma separated names
+	for _, name := range strings.Split(name, ",") {
+		if lo.Contains(BlockedDemuxers, name) && !lo.Contains(AllowedDemuxers, name) {
+			return &UnsafeInputError{Demuxer: name}
+		}
+	}
+
+	return nil
+}
+
+func checkProtocol(output string) error {
+	// check output
+	match := blockedProtocolPattern.FindStringSubmatch(output)
+	if match != nil {
+		return &UnsafeInputError{Protocol: match[1]}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+const playlist = `#EXTM3U
+#EXT-X-TARGETDURATION:10
+#EXTINF:10.0,
+http://169.254.169.254/latest/meta-data/
+#EXT-X-ENDLIST
+`
+
+const concatList = `ffconcat version 1.0
+file /etc/passwd
+`
+
+func TestAnalyzeUnsafeInput(t *testing.T) {
+	for _, item := range []struct {
+		input   string
+		demuxer string
+	}{
+		{playlist, "hls"},
+		{"\xef\xbb\xbf" + playlist, "hls"},
+		{concatList, "concat"},
+		{`<?xml version="1.0"?><MPD xmlns="urn:mpeg:dash:schema:mpd:2011"></MPD>`, "dash"},
+		{`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011"></MPD>`, "dash"},
+		{"v=0\r\no=- 0 0 IN IP4 10.0.0.1\r\nm=video 5004 RTP/AVP 96\r\n", "sdp"},
+	} {
+		report, err := Analyze(nil, strings.NewReader(item.input))
+		assert.Nil(t, report)
+		assert.Equal(t, &UnsafeInputError{Demuxer: item.demuxer}, err)
+
+		err = Convert(nil, strings.NewReader(item.input), io.Discard, ConvertOptions{
+			Preset: AudioMP3VBRStandard,
+		})
+		assert.Equal(t, &UnsafeInputError{Demuxer: item.demuxer}, err)
+	}
+}
+
+func TestCheckInput(t *testing.T) {
+	AllowedDemuxers = []string{"hls"}
+	defer func() {
+		AllowedDemuxers = nil
+	}()
+
+	r, err := checkInput(strings.NewReader(playlist))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, r)
+	assert.NoError(t, err)
+	assert.Equal(t, playlist, buf.String())
+
+	err = checkDemuxer("mov,mp4,m4a,3gp,3g2,mj2")
+	assert.NoError(t, err)
+
+	err = checkDemuxer("concat")
+	assert.Equal(t, &UnsafeInputError{Demuxer: "concat"}, err)
+	assert.Equal(t, `unsafe input: blocked demuxer "concat"`, err.Error())
+}
+
+func TestCheckProtocol(t *testing.T) {
+	err := checkProtocol("[hls @ 0x7f] Protocol 'http' not on whitelist 'file,pipe'!\n")
+	assert.Equal(t, &UnsafeInputError{Protocol: "http"}, err)
+	assert.Equal(t, `unsafe input: blocked protocol "http"`, err.Error())
+
+	err = checkProtocol("invalid data found when processing input")
+	assert.NoError(t, err)
+}
+
+func TestConvertProbeInput(t *testing.T) {
+	var commands []run.Command
+	Runner = run.Func(func(ctx context.Context, cmd run.Command) error {
+		commands = append(commands, cmd)
+		if cmd.Name == "ffprobe" {
+			_, _ = cmd.Stdout.Write([]byte(`{"format": {"format_name": "imf"}}`))
+		}
+		return nil
+	})
+	defer func() {
+		Runner = run.Default
+	}()
+
+	file, err := os.CreateTemp("", "input-")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	_, err = file.WriteString(`<CompositionPlaylist xmlns="http://www.smpte-ra.org/schemas/2067-3/2016">`)
+	assert.NoError(t, err)
+
+	err = Convert(nil, file, io.Discard, ConvertOptions{
+		Preset: AudioMP3VBRStandard,
+	})
+	assert.Equal(t, &UnsafeInputError{Demuxer: "imf"}, err)
+	assert.Len(t, commands, 1)
+	assert.Equal(t, []string{
+		"-print_format", "json", "-show_format", "-show_error",
+		"-protocol_whitelist", "file,pipe", file.Name(),
+	}, commands[0].Args)
+}
@@ -0,0 +1,484 @@
+// Package imageproxy provides an HTTP handler that transforms images on the fly.
+package imageproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/cache"
+)
+
+// Handler serves transformed images using URLs of the form
+// "/<signature>/<params>/<name>". See ParseParams for the parameter format and
+// URL for how to generate signed URLs.
+type Handler struct {
+	// The source of the original images.
+	Source Source
+
+	// The key used to verify signatures. If absent, signatures are not
+	// verified.
+	Key []byte
+
+	// The optional store used to cache transformed images.
+	Cache cache.Store
+
+	// The formats negotiated using the Accept header in order of preference.
+	// Defaults to AVIF and WebP. Only GIF and WebP are negotiated for animated
+	// images as the other formats drop the animation.
+	Formats []string
+
+	// The maximum requested width and height.
+	MaxWidth  int
+	MaxHeight int
+
+	// The limits applied to the original images.
+	Limits mediakit.Limits
+
+	// The optional pool used to run conversions.
+	Pool *mediakit.Pool
+
+	// The directory for temporary files, defaults to os.TempDir().
+	TempDir string
+
+	// The max age set in the Cache-Control header.
+	MaxAge time.Duration
+
+	// The logger used for internal errors, which are not sent to clients.
+	// Defaults to the standard logger.
+	ErrorLog *log.Logger
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// check method
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// split path
+	segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(segments) != 3 || !fs.ValidPath(segments[2]) {
+		http.NotFound(w, r)
+		return
+	}
+	signature, rawParams, name := segments[0], segments[1], segments[2]
+
+	// verify signature
+	if h.Key != nil && !Verify(h.Key, "/"+rawParams+"/"+name, signature) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	// parse params
+	params, err := ParseParams(rawParams)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// check size
+	if (h.MaxWidth > 0 && params.Width > h.MaxWidth) || (h.MaxHeight > 0 && params.Height > h.MaxHeight) {
+		http.Error(w, "size too large", http.StatusBadRequest)
+		return
+	}
+
+	// open source
+	source, modTime, err := h.Source.Open(r.Context(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		h.fail(w, err)
+		return
+	}
+	defer source.Close()
+
+	// get version from the source metadata or otherwise the contents
+	var input *os.File
+	version := sourceVersion(name, source, modTime)
+	if version == "" {
+		input, err = h.load(source)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+		defer os.Remove(input.Name())
+		defer input.Close()
+		version, err = cache.Hash(input)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+	}
+
+	// get accepted formats, the format is negotiated when converting
+	var accepted []string
+	if params.Format == "" || params.Format == Auto {
+		params.Format = Auto
+		accepted = h.acceptedFormats(r)
+		w.Header().Add("Vary", "Accept")
+	}
+
+	// compute key and etag
+	key := "imageproxy:" + version + ":" + params.String()
+	if params.Format == Auto {
+		key += ":" + strings.Join(accepted, ",")
+	}
+	sum := sha256.Sum256([]byte(key))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// set headers
+	w.Header().Set("ETag", etag)
+	if h.MaxAge > 0 {
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.MaxAge.Seconds())))
+	}
+
+	// skip conversion if not modified
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// check cache
+	var buf []byte
+	var ok bool
+	if h.Cache != nil {
+		buf, ok, err = h.Cache.Get(key)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+	}
+
+	// otherwise, convert image
+	if !ok {
+		// load source
+		if input == nil {
+			input, err = h.load(source)
+			if err != nil {
+				h.fail(w, err)
+				return
+			}
+			defer os.Remove(input.Name())
+			defer input.Close()
+		}
+
+		// convert image
+		buf, err = h.image(r.Context(), key, input, params, accepted)
+		if mediakit.ErrLimitExceeded.Is(err) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
+			h.fail(w, err)
+			return
+		}
+	}
+
+	// serve image
+	w.Header().Set("Content-Type", mediakit.Detect(buf, false))
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(buf))
+}
+
+func (h *Handler) load(source io.Reader) (*os.File, error) {
+	// create temporary file
+	input, err := os.CreateTemp(h.TempDir, "imageproxy-")
+	if err != nil {
+		return nil, err
+	}
+
+	// copy source and rewind
+	_, err = io.Copy(input, source)
+	if err == nil {
+		_, err = input.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = input.Close()
+		_ = os.Remove(input.Name())
+		return nil, err
+	}
+
+	return input, nil
+}
+
+func (h *Handler) image(ctx context.Context, key string, input *os.File, params Params, accepted []string) ([]byte, error) {
+	// resolve format
+	sizer, crop := params.Sizer()
+	if params.Format == Auto {
+		format, err := negotiate(accepted, input, crop)
+		if err != nil {
+			return nil, err
+		}
+		params.Format = format
+	}
+
+	// create output
+	output, err := os.CreateTemp(h.TempDir, "imageproxy-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	// prepare conversion
+	preset := formatPresets[params.Format]
+	opts := mediakit.ImageOptions{
+		Quality: params.Quality,
+		Crop:    crop,
+		Limits:  h.Limits,
+	}
+
+	// convert image
+	if h.Pool != nil {
+		err = h.Pool.ConvertImageWith(ctx, input, output, preset, sizer, opts)
+	} else {
+		err = mediakit.ConvertImageWith(ctx, input, output, preset, sizer, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// read output
+	buf, err := io.ReadAll(output)
+	if err != nil {
+		return nil, err
+	}
+
+	// cache image
+	if h.Cache != nil {
+		err = h.Cache.Set(key, buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+func (h *Handler) fail(w http.ResponseWriter, err error) {
+	// log error
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf("imageproxy: %s", err.Error())
+	} else {
+		log.Printf("imageproxy: %s", err.Error())
+	}
+
+	// write generic error
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
+
+func (h *Handler) acceptedFormats(r *http.Request) []string {
+	// get formats
+	formats := h.Formats
+	if formats == nil {
+		formats = []string{AVIF, WebP}
+	}
+
+	// filter accepted formats
+	accepted := acceptedTypes(r.Header.Get("Accept"))
+	return lo.Filter(formats, func(format string, _ int) bool {
+		return lo.Contains(accepted, formatTypes[format])
+	})
+}
+
+func negotiate(accepted []string, input *os.File, crop bool) (string, error) {
+	// detect input type
+	buf := make([]byte, mediakit.DetectBytes)
+	n, err := input.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	typ := mediakit.Detect(buf[:n], false)
+
+	// check animation, which is dropped when cropping
+	var anim bool
+	if !crop {
+		anim, err = animated(input, typ)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// use first accepted format, only GIF and WebP keep animations
+	for _, format := range accepted {
+		if !anim || format == WebP || format == GIF {
+			return format, nil
+		}
+	}
+
+	// otherwise, keep lossless and animated formats
+	switch {
+	case anim, typ == "image/gif":
+		return GIF, nil
+	case typ == "image/png":
+		return PNG, nil
+	default:
+		return JPG, nil
+	}
+}
+
+func acceptedTypes(header string) []string {
+	// parse media ranges
+	var list []string
+	for _, item := range strings.Split(header, ",") {
+		// split parameters
+		parts := strings.Split(item, ";")
+		typ := strings.ToLower(strings.TrimSpace(parts[0]))
+
+		// skip rejected types
+		rejected := false
+		for _, param := range parts[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if key == "q" {
+				q, err := strconv.ParseFloat(value, 64)
+				rejected = err != nil || q <= 0
+			}
+		}
+		if !rejected && typ != "" {
+			list = append(list, typ)
+		}
+	}
+
+	return list
+}
+
+func sourceVersion(name string, source io.Reader, modTime time.Time) string {
+	// require modification time
+	if modTime.IsZero() {
+		return ""
+	}
+
+	// prepare version
+	version := name + "@" + strconv.FormatInt(modTime.UnixNano(), 10)
+
+	// add size if available
+	if file, ok := source.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		info, err := file.Stat()
+		if err == nil {
+			version += ":" + strconv.FormatInt(info.Size(), 10)
+		}
+	}
+
+	// hash version
+	sum := sha256.Sum256([]byte(version))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func matchETag(header, etag string) bool {
+	// check tags
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func animated(input *os.File, typ string) (bool, error) {
+	switch typ {
+	case "image/webp":
+		// check the animation flag of the extended format header
+		buf := make([]byte, 21)
+		_, err := input.ReadAt(buf, 0)
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return string(buf[12:16]) == "VP8X" && buf[20]&0x02 != 0, nil
+	case "image/gif":
+		return gifFrames(bufio.NewReader(io.NewSectionReader(input, 0, math.MaxInt64)))
+	default:
+		return false, nil
+	}
+}
+
+func gifFrames(r *bufio.Reader) (bool, error) {
+	// read header and logical screen descriptor
+	header := make([]byte, 13)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return false, ignoreEOF(err)
+	}
+
+	// skip global color table
+	if header[10]&0x80 != 0 {
+		_, err = r.Discard(3 << ((header[10] & 0x07) + 1))
+		if err != nil {
+			return false, ignoreEOF(err)
+		}
+	}
+
+	// walk blocks until a second image is found
+	var images int
+	for {
+		block, err := r.ReadByte()
+		if err != nil {
+			return false, ignoreEOF(err)
+		}
+
+		switch block {
+		case 0x21: // extension
+			_, err = r.Discard(1)
+		case 0x2c: // image descriptor
+			images++
+			if images > 1 {
+				return true, nil
+			}
+			descriptor := make([]byte, 9)
+			_, err = io.ReadFull(r, descriptor)
+			if err == nil && descriptor[8]&0x80 != 0 {
+				_, err = r.Discard(3 << ((descriptor[8] & 0x07) + 1))
+			}
+			if err == nil {
+				_, err = r.Discard(1) // LZW minimum code size
+			}
+		default: // trailer or invalid data
+			return false, nil
+		}
+		if err != nil {
+			return false, ignoreEOF(err)
+		}
+
+		// skip data sub-blocks
+		for {
+			size, err := r.ReadByte()
+			if err != nil {
+				return false, ignoreEOF(err)
+			} else if size == 0 {
+				break
+			}
+			_, err = r.Discard(int(size))
+			if err != nil {
+				return false, ignoreEOF(err)
+			}
+		}
+	}
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,240 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/cache"
+	"github.com/256dpi/mediakit/samples"
+)
+
+func TestHandler(t *testing.T) {
+	key := []byte("secret")
+	store := cache.NewMemory(10)
+
+	handler := &Handler{
+		Source: FS(fstest.MapFS{
+			"image.jpg": &fstest.MapFile{Data: samples.Read(samples.ImageJPEG)},
+		}),
+		Key:   key,
+		Cache: store,
+	}
+
+	req := httptest.NewRequest("GET", URL(key, "image.jpg", Params{Width: 256, Height: 256, Fit: Cover}), nil)
+	req.Header.Set("Accept", "image/webp,image/*;q=0.8")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/webp", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+	assert.Equal(t, "image/webp", mediakit.Detect(rec.Body.Bytes(), false))
+	assert.Equal(t, 1, store.Len())
+
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	req = httptest.NewRequest("GET", URL(key, "image.jpg", Params{Width: 256}), nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	assert.Equal(t, 2, store.Len())
+}
+
+type countingReader struct {
+	io.Reader
+	reads *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	atomic.AddInt64(r.reads, 1)
+	return r.Reader.Read(p)
+}
+
+func (r *countingReader) Close() error {
+	return nil
+}
+
+func TestHandlerVersion(t *testing.T) {
+	key := []byte("secret")
+	store := cache.NewMemory(10)
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var reads int64
+	handler := &Handler{
+		Source: SourceFunc(func(context.Context, string) (io.ReadCloser, time.Time, error) {
+			return &countingReader{
+				Reader: bytes.NewReader(samples.Read(samples.ImageJPEG)),
+				reads:  &reads,
+			}, modTime, nil
+		}),
+		Key:   key,
+		Cache: store,
+	}
+
+	req := httptest.NewRequest("GET", URL(key, "image.jpg", Params{Width: 256}), nil)
+	req.Header.Set("Accept", "image/webp")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/webp", rec.Header().Get("Content-Type"))
+	assert.Equal(t, 1, store.Len())
+	assert.NotZero(t, atomic.LoadInt64(&reads))
+
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	/* cache hit */
+
+	atomic.StoreInt64(&reads, 0)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/webp", rec.Header().Get("Content-Type"))
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.Zero(t, atomic.LoadInt64(&reads))
+
+	/* not modified */
+
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Zero(t, atomic.LoadInt64(&reads))
+
+	/* other accepted formats */
+
+	req.Header.Set("Accept", "image/avif")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	assert.Equal(t, 2, store.Len())
+
+	/* modified */
+
+	modTime = modTime.Add(time.Second)
+	req.Header.Set("Accept", "image/webp")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	assert.Equal(t, 3, store.Len())
+}
+
+func TestHandlerInternalError(t *testing.T) {
+	var logs bytes.Buffer
+	handler := &Handler{
+		Source: SourceFunc(func(context.Context, string) (io.ReadCloser, time.Time, error) {
+			return nil, time.Time{}, errors.New("connection to 10.0.0.1 refused")
+		}),
+		ErrorLog: log.New(&logs, "", 0),
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", URL(nil, "image.jpg", Params{}), nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "internal server error\n", rec.Body.String())
+	assert.Equal(t, "imageproxy: connection to 10.0.0.1 refused\n", logs.String())
+}
+
+func TestHandlerErrors(t *testing.T) {
+	key := []byte("secret")
+
+	handler := &Handler{
+		Source: FS(fstest.MapFS{
+			"image.jpg": &fstest.MapFile{Data: []byte("foo")},
+		}),
+		Key:      key,
+		MaxWidth: 1000,
+	}
+
+	for _, item := range []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{"POST", URL(key, "image.jpg", Params{}), http.StatusMethodNotAllowed},
+		{"GET", "/foo", http.StatusNotFound},
+		{"GET", URL(key, "missing.jpg", Params{}), http.StatusNotFound},
+		{"GET", URL(key, "../image.jpg", Params{}), http.StatusNotFound},
+		{"GET", URL([]byte("other"), "image.jpg", Params{}), http.StatusForbidden},
+		{"GET", "/" + Sign(key, "/w:foo/image.jpg") + "/w:foo/image.jpg", http.StatusBadRequest},
+		{"GET", URL(key, "image.jpg", Params{Width: 2000}), http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(item.method, item.path, nil))
+		assert.Equal(t, item.code, rec.Code, item.path)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	handler := &Handler{}
+
+	for _, item := range []struct {
+		sample string
+		accept string
+		crop   bool
+		format string
+	}{
+		{samples.ImageJPEG, "image/avif,image/webp", false, AVIF},
+		{samples.ImageJPEG, "image/webp", false, WebP},
+		{samples.ImageJPEG, "image/*", false, JPG},
+		{samples.ImagePNG, "image/*", false, PNG},
+		{samples.ImageGIF, "image/avif,image/webp", false, AVIF},
+		{samples.AnimationGIF, "image/avif,image/webp", false, WebP},
+		{samples.AnimationGIF, "image/avif", false, GIF},
+		{samples.AnimationGIF, "image/avif", true, AVIF},
+		{samples.AnimationWebP, "image/avif,image/webp", false, WebP},
+		{samples.AnimationWebP, "image/avif", false, GIF},
+	} {
+		input := samples.Buffer(item.sample)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", item.accept)
+		format, err := negotiate(handler.acceptedFormats(req), input, item.crop)
+		assert.NoError(t, err)
+		assert.Equal(t, item.format, format, item.sample)
+		_ = input.Close()
+	}
+}
+
+func TestAnimated(t *testing.T) {
+	for _, item := range []struct {
+		sample   string
+		typ      string
+		animated bool
+	}{
+		{samples.ImageGIF, "image/gif", false},
+		{samples.ImageWebP, "image/webp", false},
+		{samples.AnimationGIF, "image/gif", true},
+		{samples.AnimationWebP, "image/webp", true},
+	} {
+		input := samples.Buffer(item.sample)
+		ok, err := animated(input, item.typ)
+		assert.NoError(t, err)
+		assert.Equal(t, item.animated, ok, item.sample)
+		_ = input.Close()
+	}
+}
+
+func TestAcceptedTypes(t *testing.T) {
+	assert.Equal(t, []string{"image/avif", "image/webp", "*/*"}, acceptedTypes("image/avif,image/webp;q=0.9,image/png;q=0,*/*;q=0.8"))
+	assert.Empty(t, acceptedTypes(""))
+}
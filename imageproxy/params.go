@@ -0,0 +1,232 @@
+package imageproxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit"
+	"github.com/256dpi/mediakit/vips"
+)
+
+// The available fit modes.
+const (
+	// Contain resizes the image to fit within the requested size.
+	Contain = "contain"
+
+	// Cover resizes the image to fill the requested size and crops the
+	// overflow.
+	Cover = "cover"
+)
+
+// The available formats. Auto negotiates the format using the Accept header.
+const (
+	Auto = "auto"
+	JPG  = "jpg"
+	PNG  = "png"
+	WebP = "webp"
+	AVIF = "avif"
+	GIF  = "gif"
+)
+
+var formatPresets = map[string]vips.Preset{
+	JPG:  vips.JPGWeb,
+	PNG:  vips.PNGWeb,
+	WebP: vips.WebP,
+	AVIF: vips.AVIF,
+	GIF:  vips.GIFWeb,
+}
+
+var formatTypes = map[string]string{
+	JPG:  "image/jpeg",
+	PNG:  "image/png",
+	WebP: "image/webp",
+	AVIF: "image/avif",
+	GIF:  "image/gif",
+}
+
+// Params defines the transformation parameters of a request.
+type Params struct {
+	// The requested width and height. Zero values keep the original size or
+	// derive the dimension from the aspect ratio.
+	Width  int
+	Height int
+
+	// The fit mode, defaults to Contain.
+	Fit string
+
+	// The output format, defaults to Auto.
+	Format string
+
+	// The output quality (1-100), defaults to the preset quality.
+	Quality int
+}
+
+// ParseParams parses parameters in the form "w:300,h:200,fit:cover,f:webp,q:80".
+// A single "-" denotes the default parameters.
+func ParseParams(str string) (Params, error) {
+	// handle defaults
+	var params Params
+	if str == "-" {
+		return params, nil
+	}
+
+	// parse items
+	for _, item := range strings.Split(str, ",") {
+		// split item
+		key, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return params, fmt.Errorf("invalid parameter: %q", item)
+		}
+
+		// set value
+		var err error
+		switch key {
+		case "w":
+			params.Width, err = strconv.Atoi(value)
+		case "h":
+			params.Height, err = strconv.Atoi(value)
+		case "fit":
+			params.Fit = value
+		case "f":
+			params.Format = value
+		case "q":
+			params.Quality, err = strconv.Atoi(value)
+		default:
+			return params, fmt.Errorf("unknown parameter: %q", key)
+		}
+		if err != nil {
+			return params, fmt.Errorf("invalid parameter: %q", item)
+		}
+	}
+
+	// validate params
+	err := params.Validate()
+	if err != nil {
+		return params, err
+	}
+
+	return params, nil
+}
+
+// Validate will validate the parameters.
+func (p Params) Validate() error {
+	// check size
+	if p.Width < 0 || p.Height < 0 {
+		return fmt.Errorf("invalid size")
+	}
+
+	// check fit
+	if p.Fit != "" && p.Fit != Contain && p.Fit != Cover {
+		return fmt.Errorf("invalid fit: %q", p.Fit)
+	}
+
+	// check format
+	if p.Format != "" && p.Format != Auto && formatPresets[p.Format] == 0 {
+		return fmt.Errorf("invalid format: %q", p.Format)
+	}
+
+	// check quality
+	if p.Quality < 0 || p.Quality > 100 {
+		return fmt.Errorf("invalid quality")
+	}
+
+	return nil
+}
+
+// String returns the encoded parameters.
+func (p Params) String() string {
+	// collect items
+	var items []string
+	if p.Width > 0 {
+		items = append(items, "w:"+strconv.Itoa(p.Width))
+	}
+	if p.Height > 0 {
+		items = append(items, "h:"+strconv.Itoa(p.Height))
+	}
+	if p.Fit != "" {
+		items = append(items, "fit:"+p.Fit)
+	}
+	if p.Format != "" {
+		items = append(items, "f:"+p.Format)
+	}
+	if p.Quality > 0 {
+		items = append(items, "q:"+strconv.Itoa(p.Quality))
+	}
+
+	// handle defaults
+	if len(items) == 0 {
+		return "-"
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Sizer returns the sizer and whether the image should be cropped.
+func (p Params) Sizer() (mediakit.Sizer, bool) {
+	switch {
+	case p.Width > 0 && p.Height > 0 && p.Fit == Cover:
+		return func(s mediakit.Size) mediakit.Size {
+			// reduce target to avoid upscaling
+			f := lo.Min([]float64{
+				1,
+				float64(s.Width) / float64(p.Width),
+				float64(s.Height) / float64(p.Height),
+			})
+			return mediakit.Size{Width: p.Width, Height: p.Height}.Scale(f)
+		}, true
+	case p.Width > 0 && p.Height > 0:
+		return mediakit.MaxSize(mediakit.Size{Width: p.Width, Height: p.Height}), false
+	case p.Width > 0:
+		return mediakit.MaxWidth(p.Width), false
+	case p.Height > 0:
+		return mediakit.MaxHeight(p.Height), false
+	default:
+		return mediakit.KeepSize(), false
+	}
+}
+
+// Sign returns the signature for the specified path using the provided key.
+// The path has the form "/<params>/<name>".
+func Sign(key []byte, path string) string {
+	// compute mac
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns whether the signature is valid for the specified path.
+func Verify(key []byte, path, signature string) bool {
+	// decode signature
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	// compute mac
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path))
+
+	return hmac.Equal(sum, mac.Sum(nil))
+}
+
+// URL returns the signed URL path for the specified name and parameters. If
+// no key is provided, the signature is set to "_".
+func URL(key []byte, name string, params Params) string {
+	// prepare path
+	path := "/" + params.String() + "/" + strings.TrimPrefix(name, "/")
+
+	// get signature
+	signature := "_"
+	if key != nil {
+		signature = Sign(key, path)
+	}
+
+	return "/" + signature + path
+}
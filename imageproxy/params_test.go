@@ -0,0 +1,77 @@
+package imageproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit"
+)
+
+func TestParseParams(t *testing.T) {
+	params, err := ParseParams("-")
+	assert.NoError(t, err)
+	assert.Equal(t, Params{}, params)
+	assert.Equal(t, "-", params.String())
+
+	params, err = ParseParams("w:300,h:200,fit:cover,f:webp,q:80")
+	assert.NoError(t, err)
+	assert.Equal(t, Params{
+		Width:   300,
+		Height:  200,
+		Fit:     Cover,
+		Format:  WebP,
+		Quality: 80,
+	}, params)
+	assert.Equal(t, "w:300,h:200,fit:cover,f:webp,q:80", params.String())
+
+	for str, msg := range map[string]string{
+		"w":         `invalid parameter: "w"`,
+		"w:foo":     `invalid parameter: "w:foo"`,
+		"x:1":       `unknown parameter: "x"`,
+		"w:-1":      "invalid size",
+		"fit:fill":  `invalid fit: "fill"`,
+		"f:bmp":     `invalid format: "bmp"`,
+		"q:101":     "invalid quality",
+		"w:1,,h:10": `invalid parameter: ""`,
+	} {
+		_, err = ParseParams(str)
+		assert.Error(t, err)
+		assert.Equal(t, msg, err.Error())
+	}
+}
+
+func TestParamsSizer(t *testing.T) {
+	size := mediakit.Size{Width: 800, Height: 600}
+
+	for _, item := range []struct {
+		params Params
+		size   mediakit.Size
+		crop   bool
+	}{
+		{Params{}, size, false},
+		{Params{Width: 400}, mediakit.Size{Width: 400, Height: 300}, false},
+		{Params{Height: 300}, mediakit.Size{Width: 400, Height: 300}, false},
+		{Params{Width: 400, Height: 400}, mediakit.Size{Width: 400, Height: 300}, false},
+		{Params{Width: 400, Height: 400, Fit: Cover}, mediakit.Size{Width: 400, Height: 400}, true},
+		{Params{Width: 1200, Height: 1200, Fit: Cover}, mediakit.Size{Width: 600, Height: 600}, true},
+	} {
+		sizer, crop := item.params.Sizer()
+		assert.Equal(t, item.size, sizer(size), item.params.String())
+		assert.Equal(t, item.crop, crop, item.params.String())
+	}
+}
+
+func TestSign(t *testing.T) {
+	key := []byte("secret")
+
+	signature := Sign(key, "/w:300/foo.jpg")
+	assert.Len(t, signature, 43)
+	assert.True(t, Verify(key, "/w:300/foo.jpg", signature))
+	assert.False(t, Verify(key, "/w:301/foo.jpg", signature))
+	assert.False(t, Verify([]byte("other"), "/w:300/foo.jpg", signature))
+	assert.False(t, Verify(key, "/w:300/foo.jpg", "!"))
+
+	assert.Equal(t, "/"+signature+"/w:300/foo.jpg", URL(key, "/foo.jpg", Params{Width: 300}))
+	assert.Equal(t, "/_/-/foo.jpg", URL(nil, "foo.jpg", Params{}))
+}
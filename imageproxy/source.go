@@ -0,0 +1,51 @@
+package imageproxy
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// Source provides the original images. Open should return an error matching
+// fs.ErrNotExist if the image does not exist. The name, modification time and
+// size, if the reader provides a Stat method, identify the image version used
+// for caching. If the modification time is zero, the image is read and hashed
+// on every request instead.
+type Source interface {
+	Open(ctx context.Context, name string) (io.ReadCloser, time.Time, error)
+}
+
+// SourceFunc is a function that implements the Source interface.
+type SourceFunc func(ctx context.Context, name string) (io.ReadCloser, time.Time, error)
+
+// Open implements the Source interface.
+func (f SourceFunc) Open(ctx context.Context, name string) (io.ReadCloser, time.Time, error) {
+	return f(ctx, name)
+}
+
+// FS returns a source that opens images from the provided file system.
+func FS(fsys fs.FS) Source {
+	return SourceFunc(func(_ context.Context, name string) (io.ReadCloser, time.Time, error) {
+		// open file
+		file, err := fsys.Open(name)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		// get info
+		info, err := file.Stat()
+		if err != nil {
+			_ = file.Close()
+			return nil, time.Time{}, err
+		}
+
+		// check directory
+		if info.IsDir() {
+			_ = file.Close()
+			return nil, time.Time{}, fs.ErrNotExist
+		}
+
+		return file, info.ModTime(), nil
+	})
+}
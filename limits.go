@@ -0,0 +1,88 @@
+package mediakit
+
+import (
+	"math"
+
+	"github.com/256dpi/xo"
+)
+
+// ErrLimitExceeded is returned if an input exceeds the configured limits.
+var ErrLimitExceeded = xo.BF("limit exceeded")
+
+// Limits defines the limits an input must satisfy to be converted. Zero values
+// disable the respective limit.
+type Limits struct {
+	// The maximum number of pixels of an image or video frame.
+	MaxPixels int
+
+	// The maximum width and height of an image or video frame.
+	MaxWidth  int
+	MaxHeight int
+
+	// The maximum number of pages of an image or document.
+	MaxPages int
+
+	// The maximum number of animation or video frames.
+	MaxFrames int
+
+	// The maximum duration in seconds.
+	MaxDuration float64
+
+	// The maximum number of audio/video streams.
+	MaxStreams int
+
+	// The maximum audio sample rate.
+	MaxSampleRate int
+}
+
+// Check will return an error if the report exceeds the limits. The page limit
+// is not checked as reports do not carry the number of pages, it is checked by
+// ConvertImage using the loaded image instead.
+func (l Limits) Check(report *Report) error {
+	// check size
+	if l.MaxWidth > 0 && report.Width > l.MaxWidth {
+		return ErrLimitExceeded.WrapF("width %d > %d", report.Width, l.MaxWidth)
+	}
+	if l.MaxHeight > 0 && report.Height > l.MaxHeight {
+		return ErrLimitExceeded.WrapF("height %d > %d", report.Height, l.MaxHeight)
+	}
+	if l.MaxPixels > 0 && report.Width*report.Height > l.MaxPixels {
+		return ErrLimitExceeded.WrapF("pixels %d > %d", report.Width*report.Height, l.MaxPixels)
+	}
+
+	// check frames, preferring counted frames as animations may have no duration
+	frames := max(report.Frames, int(math.Round(report.Duration*report.FrameRate)))
+	if l.MaxFrames > 0 && frames > l.MaxFrames {
+		return ErrLimitExceeded.WrapF("frames %d > %d", frames, l.MaxFrames)
+	}
+
+	// check duration
+	if l.MaxDuration > 0 && report.Duration > l.MaxDuration {
+		return ErrLimitExceeded.WrapF("duration %g > %g", report.Duration, l.MaxDuration)
+	}
+
+	// check streams
+	if l.MaxStreams > 0 && len(report.Streams) > l.MaxStreams {
+		return ErrLimitExceeded.WrapF("streams %d > %d", len(report.Streams), l.MaxStreams)
+	}
+
+	// check sample rate
+	if l.MaxSampleRate > 0 && report.SampleRate > l.MaxSampleRate {
+		return ErrLimitExceeded.WrapF("sample rate %d > %d", report.SampleRate, l.MaxSampleRate)
+	}
+
+	return nil
+}
+
+func (l Limits) checkPages(pages int) error {
+	// check pages
+	if l.MaxPages > 0 && pages > l.MaxPages {
+		return ErrLimitExceeded.WrapF("pages %d > %d", pages, l.MaxPages)
+	}
+
+	return nil
+}
+
+func (l Limits) maxDimension() int {
+	return max(l.MaxWidth, l.MaxHeight)
+}
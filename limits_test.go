@@ -0,0 +1,85 @@
+package mediakit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/ffmpeg"
+	"github.com/256dpi/mediakit/samples"
+	"github.com/256dpi/mediakit/vips"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	report := &Report{
+		Width:      800,
+		Height:     450,
+		Streams:    []string{"video", "audio"},
+		Duration:   2,
+		SampleRate: 44100,
+		FrameRate:  25,
+	}
+
+	assert.NoError(t, Limits{}.Check(report))
+	assert.NoError(t, Limits{
+		MaxPixels:     800 * 450,
+		MaxWidth:      800,
+		MaxHeight:     450,
+		MaxFrames:     50,
+		MaxDuration:   2,
+		MaxStreams:    2,
+		MaxSampleRate: 44100,
+	}.Check(report))
+
+	for _, item := range []struct {
+		limits Limits
+		err    string
+	}{
+		{Limits{MaxPixels: 1000}, "pixels 360000 > 1000: limit exceeded"},
+		{Limits{MaxWidth: 500}, "width 800 > 500: limit exceeded"},
+		{Limits{MaxHeight: 300}, "height 450 > 300: limit exceeded"},
+		{Limits{MaxFrames: 10}, "frames 50 > 10: limit exceeded"},
+		{Limits{MaxDuration: 1.5}, "duration 2 > 1.5: limit exceeded"},
+		{Limits{MaxStreams: 1}, "streams 2 > 1: limit exceeded"},
+		{Limits{MaxSampleRate: 22050}, "sample rate 44100 > 22050: limit exceeded"},
+	} {
+		err := item.limits.Check(report)
+		assert.Error(t, err)
+		assert.True(t, ErrLimitExceeded.Is(err))
+		assert.Equal(t, item.err, err.Error())
+	}
+
+	err := Limits{MaxFrames: 100}.Check(&Report{
+		Width:  1,
+		Height: 1,
+		Frames: 10000,
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "frames 10000 > 100: limit exceeded", err.Error())
+}
+
+func TestLimitsConvert(t *testing.T) {
+	limits := Limits{
+		MaxPixels: 1000,
+	}
+
+	input := samples.Buffer(samples.ImagePNG)
+	output := makeBuffers(t.TempDir(), "output")[0]
+	err := ConvertImageWith(nil, input, output, vips.JPGWeb, KeepSize(), ImageOptions{Limits: limits})
+	assert.Error(t, err)
+	assert.True(t, ErrLimitExceeded.Is(err))
+
+	input = samples.Buffer(samples.VideoMOV)
+	err = ConvertVideoWith(nil, input, output, ffmpeg.VideoMP4H264AACFast, KeepSize(), 30, 48000, nil, StreamOptions{Limits: limits})
+	assert.Error(t, err)
+	assert.True(t, ErrLimitExceeded.Is(err))
+
+	limits = Limits{
+		MaxPages: 5,
+	}
+
+	input = samples.Buffer(samples.AnimationGIF)
+	err = ConvertImageWith(nil, input, output, vips.WebP, KeepSize(), ImageOptions{Limits: limits})
+	assert.Error(t, err)
+	assert.True(t, ErrLimitExceeded.Is(err))
+}
@@ -0,0 +1,454 @@
+package mediakit
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/256dpi/xo"
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/chromium"
+	"github.com/256dpi/mediakit/ffmpeg"
+	"github.com/256dpi/mediakit/vips"
+)
+
+// Tool identifies an external tool.
+type Tool int
+
+// The available tools.
+const (
+	FFmpeg Tool = iota
+	Vips
+	Chromium
+)
+
+func (t Tool) valid() bool {
+	return t >= FFmpeg && t <= Chromium
+}
+
+// PoolConfig defines the concurrency of a pool per tool. Zero values default
+// to a concurrency of one.
+type PoolConfig struct {
+	FFmpeg   int
+	Vips     int
+	Chromium int
+
+	// The optional browser pool used to capture screenshots and PDFs.
+	Browsers *chromium.Pool
+}
+
+// JobOptions defines additional job options. They are used with RunWith or
+// for all jobs of a pool returned by With.
+type JobOptions struct {
+	// The job priority. Jobs with a higher priority are started before jobs
+	// with a lower priority, jobs with the same priority are started in order.
+	// The default priority is zero.
+	Priority int
+
+	// The optional function called once a slot is acquired and the job
+	// starts running.
+	Started func()
+}
+
+// Pool runs jobs with a limited concurrency per tool.
+type Pool struct {
+	browsers *chromium.Pool
+	state    *poolState
+	job      JobOptions
+}
+
+type poolState struct {
+	mutex  sync.Mutex
+	queues [3]*poolQueue
+	seq    uint64
+}
+
+// NewPool creates and returns a new pool.
+func NewPool(config PoolConfig) *Pool {
+	// prepare pool
+	pool := &Pool{browsers: config.Browsers, state: &poolState{}}
+	for i, limit := range []int{config.FFmpeg, config.Vips, config.Chromium} {
+		pool.state.queues[i] = &poolQueue{limit: max(limit, 1)}
+	}
+
+	return pool
+}
+
+// With returns a pool that shares the slots of this pool and runs all its
+// jobs using the provided options.
+func (p *Pool) With(opts JobOptions) *Pool {
+	return &Pool{
+		browsers: p.browsers,
+		state:    p.state,
+		job:      opts,
+	}
+}
+
+// Run will run the provided function once a slot for the specified tool is
+// available. It returns the context error if the context is cancelled while
+// the job is queued. The job options of the pool are used.
+func (p *Pool) Run(ctx context.Context, tool Tool, fn func() error) error {
+	return p.RunWith(ctx, tool, p.job, fn)
+}
+
+// RunWith will run the provided function like Run using the additional
+// options.
+func (p *Pool) RunWith(ctx context.Context, tool Tool, opts JobOptions, fn func() error) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// check tool
+	if !tool.valid() {
+		return xo.F("invalid tool: %d", tool)
+	}
+
+	// acquire slot
+	err := p.acquire(ctx, tool, opts.Priority)
+	if err != nil {
+		return err
+	}
+	defer p.release(tool)
+
+	// signal start
+	if opts.Started != nil {
+		opts.Started()
+	}
+
+	return fn()
+}
+
+// Queued returns the number of queued jobs for the specified tool.
+func (p *Pool) Queued(tool Tool) int {
+	// check tool
+	if !tool.valid() {
+		return 0
+	}
+
+	// acquire mutex
+	p.state.mutex.Lock()
+	defer p.state.mutex.Unlock()
+
+	return len(p.state.queues[tool].waiters)
+}
+
+// Running returns the number of running jobs for the specified tool.
+func (p *Pool) Running(tool Tool) int {
+	// check tool
+	if !tool.valid() {
+		return 0
+	}
+
+	// acquire mutex
+	p.state.mutex.Lock()
+	defer p.state.mutex.Unlock()
+
+	return p.state.queues[tool].running
+}
+
+// Analyze will run Analyze using the tool required for the detected media
+// type.
+func (p *Pool) Analyze(ctx context.Context, input *os.File) (*Report, error) {
+	// detect media stream
+	mediaType, _, err := DetectStream(input, false)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// rewind input
+	_, err = input.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, xo.W(err)
+	}
+
+	// select tool
+	tool := FFmpeg
+	if lo.Contains(ImageTypes(), mediaType) {
+		tool = Vips
+	}
+
+	// run analysis
+	var report *Report
+	err = p.Run(ctx, tool, func() error {
+		rep, err := Analyze(ctx, input)
+		report = rep
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ConvertImage will run ConvertImage using a vips slot.
+func (p *Pool) ConvertImage(ctx context.Context, input, output *os.File, preset vips.Preset, sizer Sizer) error {
+	return p.Run(ctx, Vips, func() error {
+		return ConvertImage(ctx, input, output, preset, sizer)
+	})
+}
+
+// ConvertImageWith will run ConvertImageWith using a vips slot.
+func (p *Pool) ConvertImageWith(ctx context.Context, input, output *os.File, preset vips.Preset, sizer Sizer, opts ImageOptions) error {
+	return p.Run(ctx, Vips, func() error {
+		return ConvertImageWith(ctx, input, output, preset, sizer, opts)
+	})
+}
+
+// ConvertAudio will run ConvertAudio using a ffmpeg slot.
+func (p *Pool) ConvertAudio(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, maxSampleRate int, progress *Progress) error {
+	return p.Run(ctx, FFmpeg, func() error {
+		return ConvertAudio(ctx, input, output, preset, maxSampleRate, progress)
+	})
+}
+
+// ConvertAudioWith will run ConvertAudioWith using a ffmpeg slot.
+func (p *Pool) ConvertAudioWith(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, maxSampleRate int, progress *Progress, opts StreamOptions) error {
+	return p.Run(ctx, FFmpeg, func() error {
+		return ConvertAudioWith(ctx, input, output, preset, maxSampleRate, progress, opts)
+	})
+}
+
+// ConvertVideo will run ConvertVideo using a ffmpeg slot.
+func (p *Pool) ConvertVideo(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, sizer Sizer, maxFrameRate float64, maxSampleRate int, progress *Progress) error {
+	return p.Run(ctx, FFmpeg, func() error {
+		return ConvertVideo(ctx, input, output, preset, sizer, maxFrameRate, maxSampleRate, progress)
+	})
+}
+
+// ConvertVideoWith will run ConvertVideoWith using a ffmpeg slot.
+func (p *Pool) ConvertVideoWith(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, sizer Sizer, maxFrameRate float64, maxSampleRate int, progress *Progress, opts StreamOptions) error {
+	return p.Run(ctx, FFmpeg, func() error {
+		return ConvertVideoWith(ctx, input, output, preset, sizer, maxFrameRate, maxSampleRate, progress, opts)
+	})
+}
+
+// ExtractImage will run ExtractImage using a ffmpeg slot to extract the frame
+// and a vips slot to convert it.
+func (p *Pool) ExtractImage(ctx context.Context, input, temp, output *os.File, position float64, preset vips.Preset, sizer Sizer) error {
+	return p.ExtractImageWith(ctx, input, temp, output, position, preset, sizer, ImageOptions{})
+}
+
+// ExtractImageWith will run ExtractImageWith using a ffmpeg slot to extract
+// the frame and a vips slot to convert it.
+func (p *Pool) ExtractImageWith(ctx context.Context, input, temp, output *os.File, position float64, preset vips.Preset, sizer Sizer, opts ImageOptions) error {
+	// extract frame
+	err := p.Run(ctx, FFmpeg, func() error {
+		return extractFrame(ctx, input, temp, position, opts.Limits)
+	})
+	if err != nil {
+		return err
+	}
+
+	// convert frame
+	return p.Run(ctx, Vips, func() error {
+		return convertFrame(ctx, temp, output, preset, sizer, opts)
+	})
+}
+
+// CaptureScreenshot will run CaptureScreenshot using a chromium slot and a
+// context from the browser pool if configured.
+func (p *Pool) CaptureScreenshot(ctx context.Context, url string, output *os.File, opts chromium.ScreenshotOptions) error {
+	return p.Run(ctx, Chromium, func() error {
+		return p.withBrowser(ctx, func(ctx context.Context) error {
+			return CaptureScreenshot(ctx, url, output, opts)
+		})
+	})
+}
+
+// CapturePDF will run CapturePDF using a chromium slot and a context from the
+// browser pool if configured.
+func (p *Pool) CapturePDF(ctx context.Context, url string, output *os.File, opts chromium.PDFOptions) error {
+	return p.Run(ctx, Chromium, func() error {
+		return p.withBrowser(ctx, func(ctx context.Context) error {
+			return CapturePDF(ctx, url, output, opts)
+		})
+	})
+}
+
+// RenderAnimation will run RenderAnimation using a chromium slot and a context
+// from the browser pool if configured to render the frames, and an ffmpeg
+// slot to encode them.
+func (p *Pool) RenderAnimation(ctx context.Context, url string, temp, output *os.File, opts chromium.AnimationOptions, preset ffmpeg.Preset) error {
+	// check preset
+	err := checkAnimationPreset(preset)
+	if err != nil {
+		return err
+	}
+
+	// render frames
+	err = p.Run(ctx, Chromium, func() error {
+		return p.withBrowser(ctx, func(ctx context.Context) error {
+			return xo.W(chromium.Animate(ctx, url, temp, opts))
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// encode frames
+	return p.Run(ctx, FFmpeg, func() error {
+		return EncodeFrames(ctx, temp, output, opts.FrameRate, preset)
+	})
+}
+
+// RenderLottie will run RenderLottie using a chromium slot and a context from
+// the browser pool if configured to render the frames, and an ffmpeg slot to
+// encode them.
+func (p *Pool) RenderLottie(ctx context.Context, data []byte, temp, output *os.File, opts chromium.AnimationOptions, preset ffmpeg.Preset) error {
+	// check preset
+	err := checkAnimationPreset(preset)
+	if err != nil {
+		return err
+	}
+
+	// apply defaults
+	opts, err = chromium.LottieOptions(data, opts)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// render frames
+	err = p.Run(ctx, Chromium, func() error {
+		return p.withBrowser(ctx, func(ctx context.Context) error {
+			return xo.W(chromium.AnimateLottie(ctx, data, temp, opts))
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// encode frames
+	return p.Run(ctx, FFmpeg, func() error {
+		return EncodeFrames(ctx, temp, output, opts.FrameRate, preset)
+	})
+}
+
+func (p *Pool) withBrowser(ctx context.Context, fn func(context.Context) error) error {
+	// check browsers
+	if p.browsers == nil {
+		return fn(ctx)
+	}
+
+	// acquire browser context
+	ctx, cancel, err := p.browsers.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	return fn(ctx)
+}
+
+func (p *Pool) acquire(ctx context.Context, tool Tool, priority int) error {
+	// acquire mutex
+	p.state.mutex.Lock()
+
+	// get queue
+	queue := p.state.queues[tool]
+
+	// start immediately if possible
+	if queue.running < queue.limit && len(queue.waiters) == 0 {
+		queue.running++
+		p.state.mutex.Unlock()
+		return nil
+	}
+
+	// enqueue waiter
+	p.state.seq++
+	waiter := &poolWaiter{
+		priority: priority,
+		seq:      p.state.seq,
+		ready:    make(chan struct{}),
+	}
+	heap.Push(queue, waiter)
+
+	// release mutex
+	p.state.mutex.Unlock()
+
+	// await slot
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		// remove waiter if still queued
+		p.state.mutex.Lock()
+		if waiter.index >= 0 {
+			heap.Remove(queue, waiter.index)
+			p.state.mutex.Unlock()
+			return ctx.Err()
+		}
+		p.state.mutex.Unlock()
+
+		// otherwise, pass on granted slot
+		p.release(tool)
+
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) release(tool Tool) {
+	// acquire mutex
+	p.state.mutex.Lock()
+	defer p.state.mutex.Unlock()
+
+	// get queue
+	queue := p.state.queues[tool]
+
+	// hand over slot to next waiter if available
+	if len(queue.waiters) > 0 {
+		waiter := heap.Pop(queue).(*poolWaiter)
+		close(waiter.ready)
+		return
+	}
+
+	// otherwise, free slot
+	queue.running--
+}
+
+type poolWaiter struct {
+	priority int
+	seq      uint64
+	index    int
+	ready    chan struct{}
+}
+
+type poolQueue struct {
+	limit   int
+	running int
+	waiters []*poolWaiter
+}
+
+func (q *poolQueue) Len() int {
+	return len(q.waiters)
+}
+
+func (q *poolQueue) Less(i, j int) bool {
+	if q.waiters[i].priority != q.waiters[j].priority {
+		return q.waiters[i].priority > q.waiters[j].priority
+	}
+	return q.waiters[i].seq < q.waiters[j].seq
+}
+
+func (q *poolQueue) Swap(i, j int) {
+	q.waiters[i], q.waiters[j] = q.waiters[j], q.waiters[i]
+	q.waiters[i].index = i
+	q.waiters[j].index = j
+}
+
+func (q *poolQueue) Push(x any) {
+	waiter := x.(*poolWaiter)
+	waiter.index = len(q.waiters)
+	q.waiters = append(q.waiters, waiter)
+}
+
+func (q *poolQueue) Pop() any {
+	waiter := q.waiters[len(q.waiters)-1]
+	q.waiters = q.waiters[:len(q.waiters)-1]
+	waiter.index = -1
+	return waiter
+}
@@ -0,0 +1,229 @@
+package mediakit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/samples"
+	"github.com/256dpi/mediakit/vips"
+)
+
+func TestPoolConcurrency(t *testing.T) {
+	pool := NewPool(PoolConfig{
+		FFmpeg: 2,
+	})
+
+	var mutex sync.Mutex
+	var running, maxRunning int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Run(nil, FFmpeg, func() error {
+				mutex.Lock()
+				running++
+				maxRunning = max(maxRunning, running)
+				mutex.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, 2, maxRunning)
+	assert.Equal(t, 0, pool.Running(FFmpeg))
+	assert.Equal(t, 0, pool.Queued(FFmpeg))
+}
+
+func TestPoolPriority(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		_ = pool.Run(nil, Vips, func() error {
+			<-block
+			return nil
+		})
+		close(done)
+	}()
+
+	for pool.Running(Vips) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var mutex sync.Mutex
+	var order []int
+
+	var wg sync.WaitGroup
+	for i, priority := range []int{0, 5, 0, 10} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.RunWith(nil, Vips, JobOptions{Priority: priority}, func() error {
+				mutex.Lock()
+				order = append(order, i)
+				mutex.Unlock()
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+
+		for pool.Queued(Vips) != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	close(block)
+	<-done
+	wg.Wait()
+
+	assert.Equal(t, []int{3, 1, 0, 2}, order)
+}
+
+func TestPoolWith(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		_ = pool.Run(nil, Vips, func() error {
+			<-block
+			return nil
+		})
+		close(done)
+	}()
+
+	for pool.Running(Vips) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var first bool
+	low := make(chan struct{})
+	lowDone := make(chan struct{})
+	go func() {
+		err := pool.Run(nil, Vips, func() error {
+			select {
+			case <-low:
+				first = false
+			case <-time.After(time.Second):
+				first = true
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		close(lowDone)
+	}()
+
+	for pool.Queued(Vips) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	input := samples.Buffer(samples.ImagePNG)
+	output := makeBuffers(t.TempDir(), "output")[0]
+
+	go func() {
+		_ = pool.With(JobOptions{Priority: 1}).ConvertImage(nil, input, output, vips.JPGWeb, KeepSize())
+		close(low)
+	}()
+
+	for pool.Queued(Vips) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(block)
+	<-done
+	<-lowDone
+
+	// the prioritized conversion ran before the queued job
+	assert.False(t, first)
+	assert.Equal(t, 0, pool.With(JobOptions{}).Queued(Vips))
+}
+
+func TestPoolInvalidTool(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+
+	err := pool.Run(nil, Tool(3), func() error {
+		t.Fail()
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "invalid tool: 3", err.Error())
+	assert.Equal(t, 0, pool.Queued(Tool(-1)))
+	assert.Equal(t, 0, pool.Running(Tool(3)))
+}
+
+func TestPoolCancel(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+
+	block := make(chan struct{})
+	go func() {
+		_ = pool.Run(nil, Chromium, func() error {
+			<-block
+			return nil
+		})
+	}()
+
+	for pool.Running(Chromium) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var started int
+	opts := JobOptions{
+		Started: func() {
+			started++
+		},
+	}
+
+	err := pool.RunWith(ctx, Chromium, opts, func() error {
+		panic("not reached")
+	})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 0, pool.Queued(Chromium))
+	assert.Equal(t, 0, started)
+
+	close(block)
+
+	err = pool.RunWith(nil, Chromium, opts, func() error {
+		assert.Equal(t, 1, started)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pool.Running(Chromium))
+}
+
+func TestPoolConvertImage(t *testing.T) {
+	pool := NewPool(PoolConfig{})
+
+	input := samples.Buffer(samples.ImagePNG)
+	output := makeBuffers(t.TempDir(), "output")[0]
+
+	err := pool.ConvertImage(nil, input, output, vips.JPGWeb, KeepSize())
+	assert.NoError(t, err)
+
+	rep, err := pool.Analyze(nil, output)
+	assert.NoError(t, err)
+	assert.Equal(t, &Report{
+		MediaType:  "image/jpeg",
+		FileFormat: "jpeg",
+		Width:      800,
+		Height:     533,
+	}, rep)
+}
@@ -0,0 +1,168 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// The errors returned when a limit is hit.
+var (
+	ErrTimeLimit   = errors.New("time limit exceeded")
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+	ErrOutputLimit = errors.New("output limit exceeded")
+)
+
+// IsLimit returns whether the error is the result of a hit limit.
+func IsLimit(err error) bool {
+	return errors.Is(err, ErrTimeLimit) || errors.Is(err, ErrMemoryLimit) || errors.Is(err, ErrOutputLimit)
+}
+
+// Limits defines resource limits for a process. Zero values disable the
+// respective limit.
+type Limits struct {
+	// The maximum number of threads. Runners do not apply this limit as it
+	// is tool specific, instead the ffmpeg and vips packages translate it to
+	// the appropriate arguments or environment variables.
+	Threads int
+
+	// The CPU scheduling priority adjustment (nice value).
+	Nice int
+
+	// The maximum wall-clock time.
+	Timeout time.Duration
+
+	// The maximum address space in bytes.
+	Memory int64
+
+	// The maximum output size in bytes.
+	Output int64
+}
+
+// Merge returns the limits with zero values replaced by the specified
+// defaults.
+func (l Limits) Merge(defaults Limits) Limits {
+	if l.Threads == 0 {
+		l.Threads = defaults.Threads
+	}
+	if l.Nice == 0 {
+		l.Nice = defaults.Nice
+	}
+	if l.Timeout == 0 {
+		l.Timeout = defaults.Timeout
+	}
+	if l.Memory == 0 {
+		l.Memory = defaults.Memory
+	}
+	if l.Output == 0 {
+		l.Output = defaults.Output
+	}
+	return l
+}
+
+// Wrap will wrap the specified command arguments to apply the CPU priority
+// and memory limits. The returned command requires a POSIX shell and the
+// nice utility to be available.
+func (l Limits) Wrap(args []string) []string {
+	// apply priority
+	if l.Nice != 0 {
+		args = append([]string{"nice", "-n", strconv.Itoa(l.Nice)}, args...)
+	}
+
+	// apply memory limit
+	if l.Memory > 0 {
+		script := "ulimit -v " + strconv.FormatInt(l.Memory/1024, 10) + ` && exec "$@"`
+		args = append([]string{"sh", "-c", script, "sh"}, args...)
+	}
+
+	return args
+}
+
+var memoryErrors = []string{
+	"out of memory",
+	"cannot allocate memory",
+	"failed to allocate",
+	"bad_alloc",
+}
+
+func checkMemory(err error, stderr []byte) bool {
+	// check termination by the kernel or a failed allocation
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			switch status.Signal() {
+			case syscall.SIGKILL, syscall.SIGSEGV:
+				return true
+			}
+		}
+	}
+
+	// check messages
+	msg := strings.ToLower(string(stderr))
+	for _, str := range memoryErrors {
+		if strings.Contains(msg, str) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type limitWriter struct {
+	writer   io.Writer
+	limit    int64
+	cancel   context.CancelFunc
+	written  int64
+	exceeded bool
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	// check limit
+	if w.written+int64(len(p)) > w.limit {
+		w.exceeded = true
+		w.cancel()
+		return 0, ErrOutputLimit
+	}
+
+	// write data
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+
+	return n, err
+}
+
+type tailWriter struct {
+	writer io.Writer
+	mutex  sync.Mutex
+	tail   bytes.Buffer
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	// keep tail
+	w.mutex.Lock()
+	w.tail.Write(p)
+	if w.tail.Len() > 4096 {
+		w.tail.Next(w.tail.Len() - 4096)
+	}
+	w.mutex.Unlock()
+
+	// forward data
+	if w.writer == nil {
+		return len(p), nil
+	}
+
+	return w.writer.Write(p)
+}
+
+func (w *tailWriter) Bytes() []byte {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]byte{}, w.tail.Bytes()...)
+}
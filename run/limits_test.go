@@ -0,0 +1,119 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsMerge(t *testing.T) {
+	limits := Limits{Threads: 2, Timeout: time.Second}.Merge(Limits{
+		Threads: 4,
+		Nice:    10,
+		Memory:  1024,
+	})
+	assert.Equal(t, Limits{
+		Threads: 2,
+		Nice:    10,
+		Timeout: time.Second,
+		Memory:  1024,
+	}, limits)
+}
+
+func TestLimitsWrap(t *testing.T) {
+	args := Limits{}.Wrap([]string{"ffmpeg", "-i", "pipe:"})
+	assert.Equal(t, []string{"ffmpeg", "-i", "pipe:"}, args)
+
+	args = Limits{Nice: 10, Memory: 1 << 30}.Wrap([]string{"ffmpeg", "-i", "pipe:"})
+	assert.Equal(t, []string{
+		"sh", "-c", `ulimit -v 1048576 && exec "$@"`, "sh",
+		"nice", "-n", "10",
+		"ffmpeg", "-i", "pipe:",
+	}, args)
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	err := Default.Run(nil, Command{
+		Name:   "sleep",
+		Args:   []string{"1"},
+		Limits: Limits{Timeout: 10 * time.Millisecond},
+	})
+	assert.Equal(t, ErrTimeLimit, err)
+	assert.True(t, IsLimit(err))
+
+	err = (&Local{Limits: Limits{Timeout: 10 * time.Millisecond}}).Run(nil, Command{
+		Name: "sleep",
+		Args: []string{"1"},
+	})
+	assert.Equal(t, ErrTimeLimit, err)
+}
+
+func TestLimitsOutput(t *testing.T) {
+	var out bytes.Buffer
+	err := Default.Run(nil, Command{
+		Name:   "yes",
+		Stdout: &out,
+		Limits: Limits{Output: 1000},
+	})
+	assert.Equal(t, ErrOutputLimit, err)
+	assert.True(t, out.Len() <= 1000)
+
+	out.Reset()
+	err = Default.Run(nil, Command{
+		Name:   "echo",
+		Args:   []string{"foo"},
+		Stdout: &out,
+		Limits: Limits{Output: 1000},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n", out.String())
+}
+
+func TestLimitsMemory(t *testing.T) {
+	var out bytes.Buffer
+	err := Default.Run(nil, Command{
+		Name:   "sh",
+		Args:   []string{"-c", "ulimit -v; echo 'Cannot allocate memory' >&2; exit 1"},
+		Stdout: &out,
+		Limits: Limits{Memory: 1 << 30},
+	})
+	assert.Equal(t, ErrMemoryLimit, err)
+	assert.Equal(t, "1048576", strings.TrimSpace(out.String()))
+
+	err = Default.Run(nil, Command{
+		Name:   "false",
+		Limits: Limits{Memory: 1 << 30},
+	})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrMemoryLimit))
+
+	err = Default.Run(nil, Command{
+		Name:   "sh",
+		Args:   []string{"-c", "kill -ABRT $$"},
+		Limits: Limits{Memory: 1 << 30},
+	})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrMemoryLimit))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = Default.Run(ctx, Command{
+		Name:   "sleep",
+		Args:   []string{"1"},
+		Limits: Limits{Memory: 1 << 30},
+	})
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrMemoryLimit))
+	assert.False(t, IsLimit(err))
+
+	err = (&Local{Limits: Limits{Threads: 2}}).Run(nil, Command{
+		Name: "true",
+	})
+	assert.Error(t, err)
+}
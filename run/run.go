@@ -0,0 +1,184 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Command describes the invocation of an external tool.
+type Command struct {
+	// The tool name e.g. "ffmpeg" or "vips".
+	Name string
+
+	// The tool arguments.
+	Args []string
+
+	// Additional environment variables in the form NAME=value.
+	Env []string
+
+	// The standard streams.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Additional open files passed as fd 3 onwards.
+	ExtraFiles []*os.File
+
+	// The resource limits for the process.
+	Limits Limits
+}
+
+// Runner executes commands.
+type Runner interface {
+	Run(ctx context.Context, cmd Command) error
+}
+
+// Func is a function that implements the Runner interface.
+type Func func(ctx context.Context, cmd Command) error
+
+// Run implements the Runner interface.
+func (f Func) Run(ctx context.Context, cmd Command) error {
+	return f(ctx, cmd)
+}
+
+// Local executes commands as local processes.
+type Local struct {
+	// Paths maps tool names to binary paths. Tools without a path are
+	// looked up using the PATH environment variable.
+	Paths map[string]string
+
+	// Wrap is prepended to every command, e.g. []string{"nice", "-n", "10"}
+	// or []string{"docker", "exec", "-i", "worker"}.
+	Wrap []string
+
+	// Env is appended to the environment of every command.
+	Env []string
+
+	// Limits are the default limits for commands that do not set them. A
+	// thread limit is not supported as it is tool specific.
+	Limits Limits
+}
+
+// Run implements the Runner interface.
+func (l *Local) Run(ctx context.Context, cmd Command) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// get path
+	path := cmd.Name
+	if l.Paths[cmd.Name] != "" {
+		path = l.Paths[cmd.Name]
+	}
+
+	// check limits
+	if l.Limits.Threads != 0 {
+		return errors.New("default thread limit not supported")
+	}
+
+	// get limits
+	limits := cmd.Limits.Merge(l.Limits)
+
+	// prepare args
+	args := limits.Wrap(append([]string{path}, cmd.Args...))
+	if len(l.Wrap) > 0 {
+		args = append(append([]string{}, l.Wrap...), args...)
+	}
+
+	// apply timeout
+	parent := ctx
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	// prepare cancellable context
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// prepare command
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+	c.ExtraFiles = cmd.ExtraFiles
+
+	// apply output limit
+	var output *limitWriter
+	if limits.Output > 0 && cmd.Stdout != nil {
+		output = &limitWriter{writer: cmd.Stdout, limit: limits.Output, cancel: cancel}
+		c.Stdout = output
+	}
+
+	// capture error output to detect memory errors, if the limit is applied
+	var stderr *tailWriter
+	if limits.Memory > 0 {
+		stderr = &tailWriter{writer: cmd.Stderr}
+		c.Stderr = stderr
+	}
+
+	// set environment
+	if len(l.Env) > 0 || len(cmd.Env) > 0 {
+		c.Env = append(append(os.Environ(), l.Env...), cmd.Env...)
+	}
+
+	// run command
+	err := c.Run()
+	if err != nil {
+		if output != nil && output.exceeded {
+			return ErrOutputLimit
+		} else if limits.Timeout > 0 && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrTimeLimit
+		} else if ctx.Err() != nil {
+			return err
+		} else if stderr != nil && checkMemory(err, stderr.Bytes()) {
+			return ErrMemoryLimit
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Default is the default runner that executes commands locally.
+var Default Runner = &Local{}
+
+// Recorder is a runner that records all commands before passing them on to
+// the wrapped runner.
+type Recorder struct {
+	// The wrapped runner. If absent, commands are only recorded.
+	Runner Runner
+
+	mutex    sync.Mutex
+	commands []Command
+}
+
+// Run implements the Runner interface.
+func (r *Recorder) Run(ctx context.Context, cmd Command) error {
+	// record command
+	r.mutex.Lock()
+	r.commands = append(r.commands, cmd)
+	r.mutex.Unlock()
+
+	// run command if available
+	if r.Runner != nil {
+		return r.Runner.Run(ctx, cmd)
+	}
+
+	return nil
+}
+
+// Commands returns the recorded commands.
+func (r *Recorder) Commands() []Command {
+	// acquire mutex
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Command{}, r.commands...)
+}
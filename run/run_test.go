@@ -0,0 +1,75 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	var out bytes.Buffer
+	err := Default.Run(nil, Command{
+		Name:   "cat",
+		Stdin:  strings.NewReader("Hello World!"),
+		Stdout: &out,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello World!", out.String())
+
+	out.Reset()
+	err = (&Local{
+		Paths: map[string]string{"foo": "echo"},
+	}).Run(nil, Command{
+		Name:   "foo",
+		Args:   []string{"bar"},
+		Stdout: &out,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "bar\n", out.String())
+
+	out.Reset()
+	err = (&Local{
+		Wrap: []string{"sh", "-c", `echo "$0" "$@" "$FOO$BAR"`},
+		Env:  []string{"FOO=foo"},
+	}).Run(nil, Command{
+		Name:   "cmd",
+		Args:   []string{"arg"},
+		Env:    []string{"BAR=bar"},
+		Stdout: &out,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "cmd arg foobar\n", out.String())
+
+	err = Default.Run(nil, Command{
+		Name: "false",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "exit status 1", err.Error())
+}
+
+func TestRecorder(t *testing.T) {
+	var recorder Recorder
+	err := recorder.Run(nil, Command{
+		Name: "foo",
+		Args: []string{"bar"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Command{
+		{Name: "foo", Args: []string{"bar"}},
+	}, recorder.Commands())
+
+	var names []string
+	recorder = Recorder{
+		Runner: Func(func(ctx context.Context, cmd Command) error {
+			names = append(names, cmd.Name)
+			return nil
+		}),
+	}
+	err = recorder.Run(nil, Command{Name: "baz"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"baz"}, names)
+	assert.Len(t, recorder.Commands(), 1)
+}
@@ -23,15 +23,80 @@ type Progress struct {
 	Func func(float64)
 }
 
+// Segment defines a time range of the input in seconds. A zero duration
+// extends the segment to the end of the input.
+type Segment struct {
+	Start    float64
+	Duration float64
+}
+
+func (s Segment) check(total float64) error {
+	// check start
+	if s.Start < 0 || (total > 0 && s.Start >= total) {
+		return xo.F("segment start %g outside duration %g", s.Start, total)
+	}
+
+	return nil
+}
+
+func (s Segment) length(total float64) float64 {
+	// get remaining length
+	length := math.Max(total-s.Start, 0)
+	if s.Duration > 0 {
+		length = math.Min(length, s.Duration)
+	}
+
+	return length
+}
+
+// ImageOptions defines additional image conversion options.
+type ImageOptions struct {
+	// Override the preset quality (1-100).
+	Quality int
+
+	// Whether to fill the computed size and crop the overflow. Animations are
+	// reduced to their first frame.
+	Crop bool
+
+	// The limits checked after analyzing and before converting the input.
+	Limits Limits
+}
+
+// StreamOptions defines additional audio and video conversion options.
+type StreamOptions struct {
+	// The segment of the input to convert.
+	Segment Segment
+
+	// The limits checked after analyzing and before converting the input.
+	Limits Limits
+}
+
 // ConvertImage will convert an image using a preset and sizer. The input must
 // be processable by vips.
 func ConvertImage(ctx context.Context, input, output *os.File, preset vips.Preset, sizer Sizer) error {
+	return ConvertImageWith(ctx, input, output, preset, sizer, ImageOptions{})
+}
+
+// ConvertImageWith will convert an image using a preset, sizer and additional
+// options. The input must be processable by vips.
+func ConvertImageWith(ctx context.Context, input, output *os.File, preset vips.Preset, sizer Sizer, imageOpts ImageOptions) error {
 	// analyze input
 	report, err := vips.Analyze(ctx, input)
 	if err != nil {
 		return xo.W(err)
 	}
 
+	// check limits
+	limits := imageOpts.Limits
+	err = limits.Check(vipsReport("", report))
+	if err != nil {
+		return err
+	}
+	err = limits.checkPages(report.Pages)
+	if err != nil {
+		return err
+	}
+
 	// rewind input
 	_, err = input.Seek(0, io.SeekStart)
 	if err != nil {
@@ -49,7 +114,12 @@ func ConvertImage(ctx context.Context, input, output *os.File, preset vips.Prese
 		Preset:    preset,
 		Width:     size.Width,
 		Height:    size.Height,
-		MultiPage: report.Format == "gif" || report.Format == "webp",
+		MultiPage: !imageOpts.Crop && (report.Format == "gif" || report.Format == "webp"),
+		Crop:      imageOpts.Crop,
+		Quality:   imageOpts.Quality,
+
+		MaxDimension: limits.maxDimension(),
+		MaxPages:     limits.MaxPages,
 	}
 
 	// convert image
@@ -70,6 +140,12 @@ func ConvertImage(ctx context.Context, input, output *os.File, preset vips.Prese
 // ConvertAudio will convert/extract audio using a preset. The input
 // must be processable by ffmpeg and contain an audio stream.
 func ConvertAudio(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, maxSampleRate int, progress *Progress) error {
+	return ConvertAudioWith(ctx, input, output, preset, maxSampleRate, progress, StreamOptions{})
+}
+
+// ConvertAudioWith will convert/extract audio using a preset and additional
+// options. The input must be processable by ffmpeg and contain an audio stream.
+func ConvertAudioWith(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, maxSampleRate int, progress *Progress, streamOpts StreamOptions) error {
 	// analyze input
 	report, err := ffmpeg.Analyze(ctx, input)
 	if err != nil {
@@ -81,6 +157,20 @@ func ConvertAudio(ctx context.Context, input, output *os.File, preset ffmpeg.Pre
 		return ErrMissingStream.Wrap()
 	}
 
+	// check limits
+	limits := streamOpts.Limits
+	err = limits.Check(ffmpegReport("", report))
+	if err != nil {
+		return err
+	}
+
+	// check segment
+	segment := streamOpts.Segment
+	err = segment.check(report.Duration)
+	if err != nil {
+		return err
+	}
+
 	// rewind input
 	_, err = input.Seek(0, io.SeekStart)
 	if err != nil {
@@ -97,12 +187,19 @@ func ConvertAudio(ctx context.Context, input, output *os.File, preset ffmpeg.Pre
 	opts := ffmpeg.ConvertOptions{
 		Preset:     preset,
 		SampleRate: sampleRate,
+		Start:      segment.Start,
+		Duration:   segment.Duration,
+		MaxPixels:  limits.MaxPixels,
+		MaxStreams: limits.MaxStreams,
 	}
 
 	// set progress
 	if progress != nil {
+		length := segment.length(report.Duration)
 		opts.ProgressFunc = func(p ffmpeg.Progress) {
-			progress.Func(math.Min(p.Duration/report.Duration, 1))
+			if length > 0 {
+				progress.Func(math.Min(p.Duration/length, 1))
+			}
 		}
 		opts.ProgressRate = progress.Rate
 	}
@@ -125,6 +222,13 @@ func ConvertAudio(ctx context.Context, input, output *os.File, preset ffmpeg.Pre
 // ConvertVideo will convert/extract video/animations using a preset, sizer and max
 // frame rate. The input must be processable by ffmpeg and contain a video stream.
 func ConvertVideo(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, sizer Sizer, maxFrameRate float64, maxSampleRate int, progress *Progress) error {
+	return ConvertVideoWith(ctx, input, output, preset, sizer, maxFrameRate, maxSampleRate, progress, StreamOptions{})
+}
+
+// ConvertVideoWith will convert/extract video/animations using a preset, sizer,
+// max frame rate and additional options. The input must be processable by
+// ffmpeg and contain a video stream.
+func ConvertVideoWith(ctx context.Context, input, output *os.File, preset ffmpeg.Preset, sizer Sizer, maxFrameRate float64, maxSampleRate int, progress *Progress, streamOpts StreamOptions) error {
 	// analyze input
 	report, err := ffmpeg.Analyze(ctx, input)
 	if err != nil {
@@ -136,6 +240,20 @@ func ConvertVideo(ctx context.Context, input, output *os.File, preset ffmpeg.Pre
 		return ErrMissingStream.Wrap()
 	}
 
+	// check limits
+	limits := streamOpts.Limits
+	err = limits.Check(ffmpegReport("", report))
+	if err != nil {
+		return err
+	}
+
+	// check segment
+	segment := streamOpts.Segment
+	err = segment.check(report.Duration)
+	if err != nil {
+		return err
+	}
+
 	// rewind input
 	_, err = input.Seek(0, io.SeekStart)
 	if err != nil {
@@ -170,12 +288,19 @@ func ConvertVideo(ctx context.Context, input, output *os.File, preset ffmpeg.Pre
 		Height:     size.Height,
 		FrameRate:  frameRate,
 		SampleRate: sampleRate,
+		Start:      segment.Start,
+		Duration:   segment.Duration,
+		MaxPixels:  limits.MaxPixels,
+		MaxStreams: limits.MaxStreams,
 	}
 
 	// set progress
 	if progress != nil {
+		length := segment.length(report.Duration)
 		opts.ProgressFunc = func(p ffmpeg.Progress) {
-			progress.Func(math.Min(p.Duration/report.Duration, 1))
+			if length > 0 {
+				progress.Func(math.Min(p.Duration/length, 1))
+			}
 		}
 		opts.ProgressRate = progress.Rate
 	}
@@ -198,6 +323,23 @@ func ConvertVideo(ctx context.Context, input, output *os.File, preset ffmpeg.Pre
 // ExtractImage will extract an image using a position, preset and sizer. The
 // input must be processable by ffmpeg and contain a video stream.
 func ExtractImage(ctx context.Context, input, temp, output *os.File, position float64, preset vips.Preset, sizer Sizer) error {
+	return ExtractImageWith(ctx, input, temp, output, position, preset, sizer, ImageOptions{})
+}
+
+// ExtractImageWith will extract an image using a position, preset, sizer and
+// additional options. The input must be processable by ffmpeg and contain a
+// video stream.
+func ExtractImageWith(ctx context.Context, input, temp, output *os.File, position float64, preset vips.Preset, sizer Sizer, imageOpts ImageOptions) error {
+	// extract frame
+	err := extractFrame(ctx, input, temp, position, imageOpts.Limits)
+	if err != nil {
+		return err
+	}
+
+	return convertFrame(ctx, temp, output, preset, sizer, imageOpts)
+}
+
+func extractFrame(ctx context.Context, input, temp *os.File, position float64, limits Limits) error {
 	// analyze input
 	report, err := ffmpeg.Analyze(ctx, input)
 	if err != nil {
@@ -209,6 +351,12 @@ func ExtractImage(ctx context.Context, input, temp, output *os.File, position fl
 		return ErrMissingStream.Wrap()
 	}
 
+	// check limits
+	err = limits.Check(ffmpegReport("", report))
+	if err != nil {
+		return err
+	}
+
 	// rewind input
 	_, err = input.Seek(0, io.SeekStart)
 	if err != nil {
@@ -217,8 +365,10 @@ func ExtractImage(ctx context.Context, input, temp, output *os.File, position fl
 
 	// prepare options
 	opts := ffmpeg.ConvertOptions{
-		Preset: ffmpeg.ImagePNG, // lossless
-		Start:  report.Duration * position,
+		Preset:     ffmpeg.ImagePNG, // lossless
+		Start:      report.Duration * position,
+		MaxPixels:  limits.MaxPixels,
+		MaxStreams: limits.MaxStreams,
 	}
 
 	// convert video
@@ -227,8 +377,12 @@ func ExtractImage(ctx context.Context, input, temp, output *os.File, position fl
 		return xo.W(err)
 	}
 
+	return nil
+}
+
+func convertFrame(ctx context.Context, temp, output *os.File, preset vips.Preset, sizer Sizer, opts ImageOptions) error {
 	// convert image
-	err = ConvertImage(ctx, temp, output, preset, sizer)
+	err := ConvertImageWith(ctx, temp, output, preset, sizer, opts)
 	if err != nil {
 		return err
 	}
@@ -265,6 +419,120 @@ func CaptureScreenshot(ctx context.Context, url string, output *os.File, opts ch
 	return nil
 }
 
+// CapturePDF will print a PDF using a URL and options.
+func CapturePDF(ctx context.Context, url string, output *os.File, opts chromium.PDFOptions) error {
+	// print PDF
+	buf, err := chromium.PrintPDF(ctx, url, opts)
+	if err != nil {
+		return err
+	}
+
+	// copy document
+	_, err = output.Write(buf)
+	if err != nil {
+		return err
+	}
+
+	// sync and rewind file
+	err = syncAndRewind(output)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RenderAnimation will render the animation at the URL to PNG frames in the
+// temporary file and encode them using EncodeFrames.
+func RenderAnimation(ctx context.Context, url string, temp, output *os.File, opts chromium.AnimationOptions, preset ffmpeg.Preset) error {
+	// check preset
+	err := checkAnimationPreset(preset)
+	if err != nil {
+		return err
+	}
+
+	// render frames
+	err = chromium.Animate(ctx, url, temp, opts)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	return EncodeFrames(ctx, temp, output, opts.FrameRate, preset)
+}
+
+// RenderLottie will render the Lottie animation to PNG frames in the temporary
+// file and encode them using EncodeFrames.
+func RenderLottie(ctx context.Context, data []byte, temp, output *os.File, opts chromium.AnimationOptions, preset ffmpeg.Preset) error {
+	// check preset
+	err := checkAnimationPreset(preset)
+	if err != nil {
+		return err
+	}
+
+	// apply defaults
+	opts, err = chromium.LottieOptions(data, opts)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// render frames
+	err = chromium.AnimateLottie(ctx, data, temp, opts)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	return EncodeFrames(ctx, temp, output, opts.FrameRate, preset)
+}
+
+// EncodeFrames will encode a stream of PNG frames at the specified frame rate
+// (defaults to 30) using one of the VideoMP4H264AACFast, AnimationGIF or
+// AnimationWebP presets.
+func EncodeFrames(ctx context.Context, frames, output *os.File, frameRate float64, preset ffmpeg.Preset) error {
+	// check preset
+	err := checkAnimationPreset(preset)
+	if err != nil {
+		return err
+	}
+
+	// get frame rate
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+
+	// sync and rewind frames
+	err = syncAndRewind(frames)
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// encode frames
+	err = ffmpeg.Convert(ctx, frames, output, ffmpeg.ConvertOptions{
+		Preset:         preset,
+		InputFormat:    "image2pipe",
+		InputFrameRate: frameRate,
+	})
+	if err != nil {
+		return xo.W(err)
+	}
+
+	// sync and rewind file
+	err = syncAndRewind(output)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func checkAnimationPreset(preset ffmpeg.Preset) error {
+	switch preset {
+	case ffmpeg.VideoMP4H264AACFast, ffmpeg.AnimationGIF, ffmpeg.AnimationWebP:
+		return nil
+	default:
+		return xo.F("unsupported animation preset: %s", preset)
+	}
+}
+
 func syncAndRewind(file *os.File) error {
 	// sync file
 	err := file.Sync()
@@ -187,6 +187,39 @@ func TestCaptureScreenshot(t *testing.T) {
 	assert.Equal(t, "image/png", Detect(buf, false))
 }
 
+func TestCapturePDF(t *testing.T) {
+	output := makeBuffers(t.TempDir(), "output")[0]
+
+	err := CapturePDF(nil, "https://example.org", output, chromium.PDFOptions{})
+	assert.NoError(t, err)
+
+	buf := make([]byte, DetectBytes)
+	_, err = io.ReadFull(output, buf)
+	assert.Equal(t, "application/pdf", Detect(buf, false))
+}
+
+func TestRenderAnimation(t *testing.T) {
+	buffers := makeBuffers(t.TempDir(), "temp", "output")
+	temp, output := buffers[0], buffers[1]
+
+	url := `data:text/html,<body style="margin:0"><div style="width:100px;height:100px;background:red;animation:spin 1s linear"></div><style>@keyframes spin{to{transform:rotate(360deg)}}</style></body>`
+	err := RenderAnimation(nil, url, temp, output, chromium.AnimationOptions{
+		Width:     200,
+		Height:    200,
+		FrameRate: 10,
+		Duration:  time.Second,
+	}, ffmpeg.AnimationGIF)
+	assert.NoError(t, err)
+
+	buf := make([]byte, DetectBytes)
+	_, err = io.ReadFull(output, buf)
+	assert.Equal(t, "image/gif", Detect(buf, false))
+
+	err = RenderAnimation(nil, url, temp, output, chromium.AnimationOptions{}, ffmpeg.ImagePNG)
+	assert.Error(t, err)
+	assert.Equal(t, "unsupported animation preset: ImagePNG", err.Error())
+}
+
 func makeBuffers(dir string, names ...string) []*os.File {
 	var list []*os.File
 	for _, name := range names {
@@ -198,3 +231,24 @@ func makeBuffers(dir string, names ...string) []*os.File {
 	}
 	return list
 }
+
+func TestSegmentLength(t *testing.T) {
+	assert.Equal(t, 10.0, Segment{}.length(10))
+	assert.Equal(t, 7.0, Segment{Start: 3}.length(10))
+	assert.Equal(t, 2.0, Segment{Start: 3, Duration: 2}.length(10))
+	assert.Equal(t, 1.0, Segment{Start: 9, Duration: 2}.length(10))
+	assert.Equal(t, 0.0, Segment{Start: 12}.length(10))
+}
+
+func TestSegmentCheck(t *testing.T) {
+	assert.NoError(t, Segment{}.check(10))
+	assert.NoError(t, Segment{Start: 9}.check(10))
+	assert.NoError(t, Segment{Start: 5}.check(0))
+
+	err := Segment{Start: 10}.check(10)
+	assert.Error(t, err)
+	assert.Equal(t, "segment start 10 outside duration 10", err.Error())
+
+	err = Segment{Start: -1}.check(10)
+	assert.Error(t, err)
+}
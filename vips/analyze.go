@@ -5,11 +5,20 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os/exec"
 	"strconv"
 	"strings"
+
+	"github.com/256dpi/mediakit/cache"
+	"github.com/256dpi/mediakit/run"
 )
 
+// Runner is the runner used to execute the vips and vipsheader utilities.
+var Runner run.Runner = run.Default
+
+// Cache is an optional store used to cache analysis reports of seekable inputs
+// by their content hash.
+var Cache cache.Store
+
 // Report is an analysis report.
 type Report struct {
 	Width  int
@@ -22,15 +31,51 @@ type Report struct {
 }
 
 // Analyze will run the vipsheader utility on the specified input and
-// return the parsed report.
+// return the parsed report. If a cache is configured, reports of seekable
+// inputs are cached.
 func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	// ensure context
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	// analyze input if cache is absent
+	if Cache == nil {
+		return analyze(ctx, r)
+	}
+
+	// check cache
+	var report Report
+	key, ok, err := cache.Lookup(Cache, "vips:", r, &report)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		return &report, nil
+	}
+
+	// analyze input
+	rep, err := analyze(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// cache report
+	if key != "" {
+		err = cache.Save(Cache, key, rep)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rep, nil
+}
+
+func analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	// prepare command
-	cmd := exec.CommandContext(ctx, "vipsheader", "-a", "stdin")
+	cmd := run.Command{
+		Name: "vipsheader",
+		Args: []string{"-a", "stdin"},
+	}
 
 	// set input
 	cmd.Stdin = r
@@ -41,7 +86,7 @@ func Analyze(ctx context.Context, r io.Reader) (*Report, error) {
 	cmd.Stderr = &stderr
 
 	// run command
-	err := cmd.Run()
+	err := Runner.Run(ctx, cmd)
 	if err != nil {
 		if stderr.Len() > 0 {
 			return nil, fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
@@ -0,0 +1,132 @@
+package vips
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+var nicknamePattern = regexp.MustCompile(`^\s*Vips\w+ \((\w+)\)`)
+
+var saverSuffixes = map[string]string{
+	".jpg":  "jpegsave",
+	".png":  "pngsave",
+	".webp": "webpsave",
+	".gif":  "gifsave",
+	".avif": "heifsave",
+}
+
+// Features describes the installed vips utilities.
+type Features struct {
+	Version    string
+	Operations []string
+	Loaders    []string
+	Savers     []string
+}
+
+// Capabilities will run the vips utility to discover its version and the
+// available operations, loaders and savers.
+func Capabilities(ctx context.Context) (*Features, error) {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// get version
+	version, err := query(ctx, "--version")
+	if err != nil {
+		return nil, err
+	}
+
+	// get operations
+	list, err := query(ctx, "-l")
+	if err != nil {
+		return nil, err
+	}
+
+	// prepare features
+	features := Features{
+		Version: strings.TrimPrefix(strings.TrimSpace(version), "vips-"),
+	}
+
+	// parse operations
+	for _, line := range strings.Split(list, "\n") {
+		match := nicknamePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		name := match[1]
+		features.Operations = append(features.Operations, name)
+
+		// strip variants
+		name = strings.TrimSuffix(name, "_source")
+		name = strings.TrimSuffix(name, "_target")
+		name = strings.TrimSuffix(name, "_buffer")
+
+		// collect loaders and savers
+		if strings.HasSuffix(name, "load") {
+			features.Loaders = append(features.Loaders, name)
+		} else if strings.HasSuffix(name, "save") {
+			features.Savers = append(features.Savers, name)
+		}
+	}
+
+	// dedupe lists
+	features.Loaders = lo.Uniq(features.Loaders)
+	features.Savers = lo.Uniq(features.Savers)
+
+	return &features, nil
+}
+
+// Supports returns an error if the specified preset cannot be used with the
+// available operations and savers.
+func (f *Features) Supports(p Preset) error {
+	// check preset
+	if !p.Valid() {
+		return fmt.Errorf("invalid preset")
+	}
+
+	// check operation
+	var missing []string
+	if !lo.Contains(f.Operations, "thumbnail_source") {
+		missing = append(missing, "operation thumbnail_source")
+	}
+
+	// check saver
+	saver := saverSuffixes[p.Arg()[:strings.Index(p.Arg(), "[")]]
+	if !lo.Contains(f.Savers, saver) {
+		missing = append(missing, "saver "+saver)
+	}
+
+	// check missing
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func query(ctx context.Context, arg string) (string, error) {
+	// run command
+	var stdout, stderr bytes.Buffer
+	err := Runner.Run(ctx, run.Command{
+		Name:   "vips",
+		Args:   []string{arg},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
+		}
+		return "", fmt.Errorf("vips: %s", err.Error())
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,63 @@
+package vips
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+const listOutput = `VipsOperation (operation), operations
+  VipsResample (resample), resample operations
+    VipsThumbnail (thumbnail_base), thumbnail generation
+      VipsThumbnailSource (thumbnail_source), generate thumbnail from source
+  VipsForeign (foreign), load and save images
+    VipsForeignLoad (fileload), file loaders
+      VipsForeignLoadJpegFile (jpegload), load jpeg from file (.jpg, .jpeg, .jpe), priority=50, is_a, get_flags, header, load
+      VipsForeignLoadJpegSource (jpegload_source), load image from jpeg source, priority=50, is_a_source, get_flags, header, load
+      VipsForeignLoadHeifSource (heifload_source), load a HEIF image, priority=0, is_a_source, get_flags, header, load
+    VipsForeignSave (filesave), file savers
+      VipsForeignSaveJpegTarget (jpegsave_target), save image to jpeg target (.jpg, .jpeg, .jpe), rgb cmyk
+      VipsForeignSavePngTarget (pngsave_target), save image to target as PNG (.png), rgb alpha
+`
+
+func fakeRunner() run.Runner {
+	return run.Func(func(ctx context.Context, cmd run.Command) error {
+		out := "vips-8.14.1\n"
+		if cmd.Args[0] == "-l" {
+			out = listOutput
+		}
+		_, err := io.WriteString(cmd.Stdout, out)
+		return err
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	Runner = fakeRunner()
+	defer func() {
+		Runner = run.Default
+	}()
+
+	features, err := Capabilities(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &Features{
+		Version: "8.14.1",
+		Operations: []string{
+			"operation", "resample", "thumbnail_base", "thumbnail_source",
+			"foreign", "fileload", "jpegload", "jpegload_source", "heifload_source",
+			"filesave", "jpegsave_target", "pngsave_target",
+		},
+		Loaders: []string{"fileload", "jpegload", "heifload"},
+		Savers:  []string{"filesave", "jpegsave", "pngsave"},
+	}, features)
+
+	assert.NoError(t, features.Supports(JPGWeb))
+	assert.NoError(t, features.Supports(PNGWeb))
+
+	err = features.Supports(WebP)
+	assert.Error(t, err)
+	assert.Equal(t, "missing saver webpsave", err.Error())
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/256dpi/mediakit/run"
 )
 
 // Preset represents a conversion preset.
@@ -34,6 +37,11 @@ const (
 	// GIFWeb is a Web optimized preset for GIFs with stripped metadata.
 	// `vips gifsave`
 	GIFWeb
+
+	// AVIF is a Web optimized preset for AVIF with stripped metadata and SRGB
+	// color space.
+	// `vips heifsave`
+	AVIF
 )
 
 // Valid returns whether the preset is valid.
@@ -41,6 +49,35 @@ func (p Preset) Valid() bool {
 	return p.Arg() != ""
 }
 
+// String returns the name of the preset.
+func (p Preset) String() string {
+	switch p {
+	case JPGWeb:
+		return "JPGWeb"
+	case PNGWeb:
+		return "PNGWeb"
+	case WebP:
+		return "WebP"
+	case GIFWeb:
+		return "GIFWeb"
+	case AVIF:
+		return "AVIF"
+	default:
+		return "Preset(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// ParsePreset returns the preset with the specified name. The name is matched
+// case-insensitively. Zero is returned if no preset matches.
+func ParsePreset(name string) Preset {
+	for p := Preset(1); p.Valid(); p++ {
+		if strings.EqualFold(p.String(), name) {
+			return p
+		}
+	}
+	return 0
+}
+
 // Arg returns the vips argument for the preset.
 func (p Preset) Arg() string {
 	switch p {
@@ -52,11 +89,15 @@ func (p Preset) Arg() string {
 		return ".webp[Q=90,strip,smart_subsample]"
 	case GIFWeb:
 		return ".gif[strip]"
+	case AVIF:
+		return ".avif[Q=60,strip]"
 	default:
 		return ""
 	}
 }
 
+var qualityPattern = regexp.MustCompile(`Q=\d+`)
+
 // ConvertOptions defines conversion options.
 type ConvertOptions struct {
 	// Select the desired preset.
@@ -79,6 +120,18 @@ type ConvertOptions struct {
 
 	// Whether to attempt multi-page conversion.
 	MultiPage bool
+
+	// Override the preset quality (1-100) if supported by the format.
+	Quality int
+
+	// Reject inputs with a larger width or height.
+	MaxDimension int
+
+	// Limit the number of pages loaded for multi-page conversion.
+	MaxPages int
+
+	// Limit the resources used by the vips process.
+	Limits run.Limits
 }
 
 // Convert will run the vips utility to convert the specified input to the
@@ -95,11 +148,17 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 		return fmt.Errorf("invalid preset")
 	}
 
+	// get output
+	output := opts.Preset.Arg()
+	if opts.Quality > 0 {
+		output = qualityPattern.ReplaceAllString(output, "Q="+strconv.Itoa(opts.Quality))
+	}
+
 	// prepare args
 	args := []string{
 		"thumbnail_source",
 		"[descriptor=0]",
-		opts.Preset.Arg(),
+		output,
 		strconv.Itoa(opts.Width),
 	}
 
@@ -108,6 +167,9 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 	if opts.MultiPage && !opts.Crop && rFile != nil && rFile.Name() != "" {
 		args[0] = "thumbnail"
 		args[1] = rFile.Name() + "[n=-1]"
+		if opts.MaxPages > 0 {
+			args[1] = rFile.Name() + "[n=" + strconv.Itoa(opts.MaxPages) + "]"
+		}
 	} else if opts.MultiPage {
 		return fmt.Errorf("multi-page input requires file input and no cropping")
 	}
@@ -133,9 +195,23 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 	}
 
 	// prepare command
-	cmd := exec.CommandContext(ctx, "vips", args...)
+	cmd := run.Command{
+		Name:   "vips",
+		Args:   args,
+		Limits: opts.Limits,
+	}
+
+	// handle threads
+	if opts.Limits.Threads > 0 {
+		cmd.Env = append(cmd.Env, "VIPS_CONCURRENCY="+strconv.Itoa(opts.Limits.Threads))
+	}
+
+	// handle max dimension
+	if opts.MaxDimension > 0 {
+		cmd.Env = append(cmd.Env, "VIPS_MAX_COORD="+strconv.Itoa(opts.MaxDimension))
+	}
 
-	// set input {
+	// set input
 	cmd.Stdin = r
 
 	// set outputs
@@ -144,8 +220,10 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 	cmd.Stderr = &stderr
 
 	// run command
-	err := cmd.Run()
-	if err != nil {
+	err := Runner.Run(ctx, cmd)
+	if run.IsLimit(err) {
+		return fmt.Errorf("vips: %w", err)
+	} else if err != nil {
 		if stderr.Len() > 0 {
 			return fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
 		}
@@ -160,20 +238,26 @@ func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ConvertOptions)
 // are standard vips CLI operations with the command name and "stdin" input
 // argument omitted.
 func Pipeline(ops [][]string, r io.Reader, w io.Writer) error {
-	// prepare stderr
-	var stderr bytes.Buffer
-
-	// prepare commands
-	var list []*exec.Cmd
-	for i, args := range ops {
-		// check args
+	// check args
+	for _, args := range ops {
 		if len(args) == 0 {
 			return fmt.Errorf("empty args")
 		}
+	}
+
+	// prepare stderr
+	var stderr safeBuffer
 
+	// prepare commands
+	var list []run.Command
+	var pipes []*os.File
+	for i, args := range ops {
 		// create command
-		cmd := exec.Command("vips", append([]string{args[0], "stdin"}, args[1:]...)...)
-		cmd.Stdin = r
+		cmd := run.Command{
+			Name:  "vips",
+			Args:  append([]string{args[0], "stdin"}, args[1:]...),
+			Stdin: r,
+		}
 
 		// set up stdout pipe unless it's the last command
 		if i == len(ops)-1 {
@@ -185,29 +269,43 @@ func Pipeline(ops [][]string, r io.Reader, w io.Writer) error {
 			}
 			cmd.Stdout = pipeW
 			r = pipeR
-			defer pipeW.Close()
+			pipes = append(pipes, pipeW, pipeR)
 		}
 
 		// set up stderr
 		cmd.Stderr = &stderr
 
-		// add process
+		// add command
 		list = append(list, cmd)
 	}
 
-	// start all commands
+	// run all commands
+	var wg sync.WaitGroup
+	errs := make([]error, len(list))
 	for i, cmd := range list {
-		if err := cmd.Start(); err != nil {
-			if stderr.Len() > 0 {
-				err = fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// run command
+			errs[i] = Runner.Run(context.Background(), cmd)
+
+			// close own ends of the pipes to unblock the neighbours
+			if i < len(list)-1 {
+				_ = pipes[i*2].Close()
 			}
-			return fmt.Errorf("vips: %s: %s", ops[i][0], err.Error())
-		}
+			if i > 0 {
+				_ = pipes[(i-1)*2+1].Close()
+			}
+		}()
 	}
 
 	// wait for all commands
-	for i, cmd := range list {
-		if err := cmd.Wait(); err != nil {
+	wg.Wait()
+
+	// check errors
+	for i, err := range errs {
+		if err != nil {
 			if stderr.Len() > 0 {
 				err = fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
 			}
@@ -217,3 +315,26 @@ func Pipeline(ops [][]string, r io.Reader, w io.Writer) error {
 
 	return nil
 }
+
+type safeBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *safeBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Len()
+}
+
+func (b *safeBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
@@ -2,11 +2,15 @@ package vips
 
 import (
 	"bytes"
+	"context"
+	"io"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/256dpi/mediakit/run"
 	"github.com/256dpi/mediakit/samples"
 )
 
@@ -203,6 +207,61 @@ func TestConvertOptions(t *testing.T) {
 	}
 }
 
+func TestConvertQuality(t *testing.T) {
+	recorder := &run.Recorder{}
+	Runner = recorder
+	defer func() {
+		Runner = run.Default
+	}()
+
+	for _, item := range []struct {
+		preset Preset
+		arg    string
+	}{
+		{JPGWeb, ".jpg[Q=75,strip,optimize_coding]"},
+		{WebP, ".webp[Q=75,strip,smart_subsample]"},
+		{AVIF, ".avif[Q=75,strip]"},
+		{GIFWeb, ".gif[strip]"},
+	} {
+		var buf bytes.Buffer
+		err := Convert(nil, strings.NewReader("image"), &buf, ConvertOptions{
+			Preset:  item.preset,
+			Width:   256,
+			Quality: 75,
+		})
+		assert.NoError(t, err)
+
+		commands := recorder.Commands()
+		assert.Equal(t, item.arg, commands[len(commands)-1].Args[2])
+	}
+}
+
+func TestConvertLimits(t *testing.T) {
+	var cmd run.Command
+	Runner = run.Func(func(ctx context.Context, c run.Command) error {
+		cmd = c
+		return nil
+	})
+	defer func() {
+		Runner = run.Default
+	}()
+
+	input, err := os.CreateTemp(t.TempDir(), "input")
+	assert.NoError(t, err)
+	defer input.Close()
+
+	err = Convert(nil, input, io.Discard, ConvertOptions{
+		Preset:       GIFWeb,
+		Width:        256,
+		MultiPage:    true,
+		MaxDimension: 4000,
+		MaxPages:     5,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"thumbnail", input.Name() + "[n=5]"}, cmd.Args[:2])
+	assert.Contains(t, cmd.Env, "VIPS_MAX_COORD=4000")
+}
+
 func TestConvertError(t *testing.T) {
 	var buf bytes.Buffer
 	err := Convert(nil, strings.NewReader("foo"), &buf, ConvertOptions{
@@ -224,3 +283,32 @@ func TestPipeline(t *testing.T) {
 	}, file, &buf)
 	assert.NoError(t, err)
 }
+
+func TestPipelineRunner(t *testing.T) {
+	Runner = run.Func(func(ctx context.Context, cmd run.Command) error {
+		_, err := io.Copy(cmd.Stdout, cmd.Stdin)
+		if err != nil {
+			return err
+		}
+		_, err = cmd.Stdout.Write([]byte(" " + cmd.Args[0]))
+		return err
+	})
+	defer func() {
+		Runner = run.Default
+	}()
+
+	var buf bytes.Buffer
+	err := Pipeline([][]string{
+		{"resize", ".png", "0.2"},
+		{"rotate", ".png", "90"},
+		{"flip", ".png", "horizontal"},
+	}, strings.NewReader("image"), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "image resize rotate flip", buf.String())
+}
+
+func TestParsePreset(t *testing.T) {
+	assert.Equal(t, JPGWeb, ParsePreset("JPGWeb"))
+	assert.Equal(t, AVIF, ParsePreset("avif"))
+	assert.Equal(t, Preset(0), ParsePreset("foo"))
+}
@@ -0,0 +1,63 @@
+package vips
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+// Join will run the vips utility to join the specified image files vertically
+// and write the result in the specified format (e.g. ".png" or ".jpg[Q=80]")
+// to the writer.
+func Join(ctx context.Context, files []string, format string, w io.Writer) error {
+	// ensure context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// check files
+	if len(files) == 0 {
+		return fmt.Errorf("missing files")
+	}
+
+	// check names, the array is passed as a space separated list
+	for _, file := range files {
+		if strings.ContainsAny(file, " \t\n") {
+			return fmt.Errorf("invalid file name: %q", file)
+		}
+	}
+
+	// join files
+	err := vips(ctx, w, "arrayjoin", strings.Join(files, " "), format, "--across", "1")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func vips(ctx context.Context, w io.Writer, args ...string) error {
+	// prepare command
+	var stderr bytes.Buffer
+	cmd := run.Command{
+		Name:   "vips",
+		Args:   args,
+		Stdout: w,
+		Stderr: &stderr,
+	}
+
+	// run command
+	err := Runner.Run(ctx, cmd)
+	if err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf(strings.ToLower(strings.TrimSpace(stderr.String())))
+		}
+		return fmt.Errorf("vips: %s: %s", args[0], err.Error())
+	}
+
+	return nil
+}
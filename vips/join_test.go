@@ -0,0 +1,32 @@
+package vips
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/256dpi/mediakit/run"
+)
+
+func TestJoin(t *testing.T) {
+	recorder := &run.Recorder{}
+	Runner = recorder
+	defer func() {
+		Runner = run.Default
+	}()
+
+	var buf bytes.Buffer
+	err := Join(nil, []string{"a.png", "b.png", "c.png"}, ".jpg[Q=80]", &buf)
+	assert.NoError(t, err)
+
+	commands := recorder.Commands()
+	assert.Len(t, commands, 1)
+	assert.Equal(t, []string{"arrayjoin", "a.png b.png c.png", ".jpg[Q=80]", "--across", "1"}, commands[0].Args)
+
+	err = Join(nil, nil, ".png", &buf)
+	assert.Error(t, err)
+
+	err = Join(nil, []string{"a b.png"}, ".png", &buf)
+	assert.Error(t, err)
+}